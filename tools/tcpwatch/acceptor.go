@@ -0,0 +1,53 @@
+package main
+
+import (
+	"net/netip"
+
+	gnet "github.com/shirou/gopsutil/v4/net"
+)
+
+// listenAddr is a listening socket's address; ip is "" for wildcard binds.
+type listenAddr struct {
+	ip   string
+	port uint32
+}
+
+// acceptors maps listening sockets to their owning PIDs, for
+// -show-listener.
+type acceptors map[listenAddr]int32
+
+func findAcceptors(conns []gnet.ConnectionStat) acceptors {
+	a := make(acceptors)
+	for _, c := range conns {
+		if isListener(c, normalizeState(c.Status)) {
+			a[listenAddr{listenIP(c.Laddr.IP), c.Laddr.Port}] = c.Pid
+		}
+	}
+	return a
+}
+
+// of returns the PID owning the listener that accepted c: the one bound to
+// c's local address, or else a wildcard listener on its port.
+func (a acceptors) of(c gnet.ConnectionStat) (int32, bool) {
+	if pid, ok := a[listenAddr{listenIP(c.Laddr.IP), c.Laddr.Port}]; ok {
+		return pid, true
+	}
+	pid, ok := a[listenAddr{"", c.Laddr.Port}]
+	return pid, ok
+}
+
+// listenIP normalizes an address for matching: wildcard forms become "" and
+// IPv4-mapped IPv6 addresses their IPv4 form.
+func listenIP(s string) string {
+	if s == "*" {
+		return ""
+	}
+	ip, err := netip.ParseAddr(s)
+	if err != nil {
+		return s
+	}
+	if ip.IsUnspecified() {
+		return ""
+	}
+	return ip.Unmap().String()
+}
@@ -0,0 +1,25 @@
+package main
+
+import (
+	"time"
+
+	"github.com/bulent/morzer/tools/tcpwatch/internal/render"
+)
+
+// setAges fills in each row's Age: how long tcpwatch has been seeing the
+// connection. The OS doesn't report when a socket was created, so the first
+// refresh sees every connection at age 0. Connections that disappear are
+// forgotten. Rows with the same identity share their first-seen time.
+func (st *watchState) setAges(rows []render.Row, id identity, now time.Time) {
+	seen := make(map[connKey]time.Time, len(rows))
+	for i := range rows {
+		k := id.key(rows[i])
+		first, ok := st.firstSeen[k]
+		if !ok {
+			first = now
+		}
+		seen[k] = first
+		rows[i].Age = now.Sub(first)
+	}
+	st.firstSeen = seen
+}
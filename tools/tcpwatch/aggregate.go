@@ -0,0 +1,119 @@
+package main
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"sort"
+	"time"
+
+	"github.com/bulent/morzer/tools/tcpwatch/internal/render"
+)
+
+// maxSnapshotLine bounds one NDJSON snapshot read by -aggregate; busy hosts
+// produce lines far longer than bufio.Scanner's 64 KiB default.
+const maxSnapshotLine = 64 << 20
+
+// runAggregate reads -jsonl snapshots from several producers on r and prints
+// the latest snapshot of every host as one combined set of rows, each tagged
+// with its host. Producers name themselves with -host-label; unlabelled
+// streams share one "-" host. Lines that aren't snapshots (e.g. summaries or
+// -merge-repeats records) are skipped.
+//
+// In watch mode the combined view is printed every -interval while input
+// changes, and once more at EOF; with -once only at EOF.
+func runAggregate(ctx context.Context, r io.Reader, w io.Writer, opts options, st *watchState, flush func()) error {
+	snaps := make(chan jsonSnapshot)
+	errc := make(chan error, 1)
+	go func() {
+		defer close(snaps)
+		sc := bufio.NewScanner(r)
+		sc.Buffer(nil, maxSnapshotLine)
+		for sc.Scan() {
+			var snap struct {
+				jsonSnapshot
+				Rows *[]render.Row `json:"rows"`
+			}
+			if err := json.Unmarshal(sc.Bytes(), &snap); err != nil {
+				if opts.verbose {
+					fmt.Fprintf(os.Stderr, "tcpwatch: -aggregate: skipping invalid line: %v\n", err)
+				}
+				continue
+			}
+			if snap.Rows == nil {
+				continue
+			}
+			snap.jsonSnapshot.Rows = *snap.Rows
+			select {
+			case snaps <- snap.jsonSnapshot:
+			case <-ctx.Done():
+				return
+			}
+		}
+		errc <- sc.Err()
+	}()
+
+	var tick <-chan time.Time
+	if !opts.once {
+		ticker := time.NewTicker(opts.interval)
+		defer ticker.Stop()
+		tick = ticker.C
+	}
+
+	latest := make(map[string]jsonSnapshot)
+	dirty := false
+	show := func() error {
+		dirty = false
+		err := printRows(w, opts, st, aggregateRows(latest))
+		flush()
+		return err
+	}
+	for {
+		select {
+		case <-ctx.Done():
+			return nil
+		case snap, ok := <-snaps:
+			if !ok {
+				if err := <-errc; err != nil {
+					return fmt.Errorf("-aggregate: %w", err)
+				}
+				if dirty {
+					return show()
+				}
+				return nil
+			}
+			latest[snap.Host] = snap
+			dirty = true
+		case <-tick:
+			if dirty {
+				if err := show(); err != nil {
+					return err
+				}
+			}
+		}
+	}
+}
+
+// aggregateRows flattens the latest snapshot of every host into one slice of
+// rows tagged with their host.
+func aggregateRows(latest map[string]jsonSnapshot) []render.Row {
+	hosts := make([]string, 0, len(latest))
+	for h := range latest {
+		hosts = append(hosts, h)
+	}
+	sort.Strings(hosts)
+
+	rows := []render.Row{}
+	for _, h := range hosts {
+		for _, r := range latest[h].Rows {
+			if h != "" {
+				r.Host = h
+			}
+			rows = append(rows, r)
+		}
+	}
+	return rows
+}
@@ -0,0 +1,33 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+)
+
+// errAlert is returned by printRows after reporting alerts when
+// -exit-on-alert is set.
+var errAlert = errors.New("alert raised")
+
+// printAlerts prints the events of an alert mode (-watch-new-listener,
+// -watch-only-remote-port) as text lines or, with -jsonl, JSON objects.
+func printAlerts(w io.Writer, opts options, events []connEvent) error {
+	enc := json.NewEncoder(w)
+	for _, ev := range events {
+		if opts.jsonLines {
+			if err := enc.Encode(ev); err != nil {
+				return err
+			}
+			continue
+		}
+		if _, err := fmt.Fprintln(w, formatEvent(ev)); err != nil {
+			return err
+		}
+	}
+	if len(events) > 0 && opts.exitOnAlert {
+		return errAlert
+	}
+	return nil
+}
@@ -0,0 +1,421 @@
+package main
+
+import (
+	"bufio"
+	"context"
+	"fmt"
+	"os/exec"
+	"runtime"
+	"strconv"
+	"strings"
+	"syscall"
+	"time"
+
+	gnet "github.com/shirou/gopsutil/v4/net"
+)
+
+// Connection backends. gopsutil is the default; ss and netstat shell out to
+// the system tools and parse their output, which is useful as a fallback when
+// gopsutil fails (e.g. in restricted sandboxes) and as a cross-check.
+const (
+	backendGopsutil = "gopsutil"
+	backendSS       = "ss"
+	backendNetstat  = "netstat"
+)
+
+func validateBackend(name string) error {
+	switch name {
+	case backendGopsutil, backendNetstat:
+		return nil
+	case backendSS:
+		if runtime.GOOS != "linux" {
+			return fmt.Errorf("-backend ss is only available on Linux")
+		}
+		return nil
+	default:
+		return fmt.Errorf("invalid -backend %q (want gopsutil, ss or netstat)", name)
+	}
+}
+
+// connSource lists connections of a gopsutil kind ("tcp" or "unix"). It is
+// what the default backend reads from; options.source can replace it, e.g.
+// with recorded data.
+type connSource interface {
+	Connections(ctx context.Context, kind string) ([]gnet.ConnectionStat, error)
+}
+
+// namedSource is a connSource that also knows the process names of the
+// connections it last returned.
+type namedSource interface {
+	connSource
+	procNames() procNames
+}
+
+// gopsutilSource is the default connSource.
+type gopsutilSource struct{}
+
+func (gopsutilSource) Connections(ctx context.Context, kind string) ([]gnet.ConnectionStat, error) {
+	// gopsutil uses sysctl on macOS to retrieve connection data.
+	return gnet.ConnectionsWithContext(ctx, kind)
+}
+
+// procNames maps PIDs to the process names an external tool reported.
+type procNames map[int32]string
+
+// connections lists TCP connections (and with -proto, Unix sockets) from the
+// selected backend. Backends that report process names return them too;
+// names is nil for gopsutil.
+func connections(ctx context.Context, opts options) ([]gnet.ConnectionStat, procNames, error) {
+	if opts.enumTimeout <= 0 {
+		return enumerate(ctx, opts)
+	}
+	// The OS call may ignore ctx (e.g. a stalled sysctl on macOS), so wait
+	// for it in a goroutine that is abandoned on timeout.
+	type result struct {
+		conns []gnet.ConnectionStat
+		names procNames
+		err   error
+	}
+	tctx, cancel := context.WithTimeout(ctx, opts.enumTimeout)
+	defer cancel()
+	done := make(chan result, 1)
+	go func() {
+		conns, names, err := enumerate(tctx, opts)
+		done <- result{conns, names, err}
+	}()
+	select {
+	case r := <-done:
+		if r.err != nil && ctx.Err() == nil && tctx.Err() != nil {
+			return nil, nil, errEnumTimeout(opts.enumTimeout)
+		}
+		return r.conns, r.names, r.err
+	case <-tctx.Done():
+		if err := ctx.Err(); err != nil {
+			return nil, nil, err
+		}
+		return nil, nil, errEnumTimeout(opts.enumTimeout)
+	}
+}
+
+// errEnumTimeout reports a refresh skipped by -enum-timeout. It deliberately
+// doesn't wrap context.DeadlineExceeded, which ends the watch (-duration).
+func errEnumTimeout(d time.Duration) error {
+	return fmt.Errorf("tcpwatch: warning: listing connections took longer than -enum-timeout %s; skipping this refresh", d)
+}
+
+// enumerate lists connections from the source selected by opts.
+func enumerate(ctx context.Context, opts options) ([]gnet.ConnectionStat, procNames, error) {
+	if opts.sshTarget != "" {
+		return sshConnections(ctx, opts.sshTarget)
+	}
+
+	switch opts.backend {
+	case backendSS:
+		out, err := exec.CommandContext(ctx, "ss", "-H", "-tanp").Output()
+		if err != nil {
+			return nil, nil, fmt.Errorf("ss: %w", err)
+		}
+		conns, names := parseSS(string(out))
+		return conns, names, nil
+	case backendNetstat:
+		var args []string
+		var parse func(string) ([]gnet.ConnectionStat, procNames)
+		switch runtime.GOOS {
+		case "darwin":
+			args, parse = []string{"-anv", "-p", "tcp"}, parseNetstatDarwin
+		case "windows":
+			args, parse = []string{"-ano"}, parseNetstatWindows
+		default:
+			args, parse = []string{"-tanp"}, parseNetstatLinux
+		}
+		out, err := exec.CommandContext(ctx, "netstat", args...).Output()
+		if err != nil {
+			return nil, nil, fmt.Errorf("netstat: %w", err)
+		}
+		conns, names := parse(string(out))
+		return conns, names, nil
+	default:
+		var src connSource = gopsutilSource{}
+		if opts.source != nil {
+			src = opts.source
+		}
+		kinds := []string{"tcp"}
+		switch opts.proto {
+		case "unix":
+			kinds = []string{"unix"}
+		case "all":
+			kinds = []string{"tcp", "unix"}
+		}
+		var conns []gnet.ConnectionStat
+		for _, kind := range kinds {
+			cs, err := src.Connections(ctx, kind)
+			if err != nil {
+				return nil, nil, err
+			}
+			conns = append(conns, cs...)
+		}
+		var names procNames
+		if n, ok := src.(namedSource); ok {
+			names = n.procNames()
+		}
+		return conns, names, nil
+	}
+}
+
+// parseSS parses `ss -H -tanp` output (Linux):
+//
+//	ESTAB  0  0  192.168.1.5:22  192.168.1.9:51234  users:(("sshd",pid=123,fd=3))
+//
+// Addresses use host:port with IPv6 hosts in brackets and an optional
+// %iface zone. The users:(...) column is only present when the caller may
+// see the owning process; connections without it get PID 0.
+func parseSS(out string) ([]gnet.ConnectionStat, procNames) {
+	var conns []gnet.ConnectionStat
+	names := make(procNames)
+	sc := bufio.NewScanner(strings.NewReader(out))
+	for sc.Scan() {
+		fields := strings.Fields(sc.Text())
+		if len(fields) < 5 {
+			continue
+		}
+		laddr, lok := splitHostPort(fields[3], ':')
+		raddr, rok := splitHostPort(fields[4], ':')
+		if !lok || !rok {
+			continue
+		}
+		c := gnet.ConnectionStat{
+			Family: addrFamily(laddr.IP),
+			Type:   syscall.SOCK_STREAM,
+			Laddr:  laddr,
+			Raddr:  raddr,
+			Status: ssState(fields[0]),
+		}
+		if len(fields) > 5 {
+			c.Pid = ssPID(fields[5])
+			if name := ssProcess(fields[5]); c.Pid > 0 && name != "" {
+				names[c.Pid] = name
+			}
+		}
+		conns = append(conns, c)
+	}
+	return conns, names
+}
+
+func ssState(s string) string {
+	switch s {
+	case "ESTAB":
+		return "ESTABLISHED"
+	case "UNCONN":
+		return "CLOSE"
+	case "SYN-RECV":
+		return "SYN_RECV"
+	case "FIN-WAIT-1":
+		return "FIN_WAIT1"
+	case "FIN-WAIT-2":
+		return "FIN_WAIT2"
+	default:
+		return strings.ReplaceAll(s, "-", "_")
+	}
+}
+
+func ssPID(users string) int32 {
+	i := strings.Index(users, "pid=")
+	if i < 0 {
+		return 0
+	}
+	rest := users[i+len("pid="):]
+	if j := strings.IndexAny(rest, ",)"); j >= 0 {
+		rest = rest[:j]
+	}
+	pid, err := strconv.ParseInt(rest, 10, 32)
+	if err != nil {
+		return 0
+	}
+	return int32(pid)
+}
+
+// ssProcess returns the first process name in an ss users:(("name",...))
+// column.
+func ssProcess(users string) string {
+	_, rest, ok := strings.Cut(users, `(("`)
+	if !ok {
+		return ""
+	}
+	name, _, _ := strings.Cut(rest, `"`)
+	return name
+}
+
+// parseNetstatLinux parses `netstat -tanp` output (Linux net-tools):
+//
+//	tcp  0  0  0.0.0.0:22  0.0.0.0:*  LISTEN  123/sshd
+//
+// The last column is "PID/Program name", or "-" when not visible.
+func parseNetstatLinux(out string) ([]gnet.ConnectionStat, procNames) {
+	var conns []gnet.ConnectionStat
+	names := make(procNames)
+	sc := bufio.NewScanner(strings.NewReader(out))
+	for sc.Scan() {
+		fields := strings.Fields(sc.Text())
+		if len(fields) < 6 || !strings.HasPrefix(fields[0], "tcp") {
+			continue
+		}
+		laddr, lok := splitHostPort(fields[3], ':')
+		raddr, rok := splitHostPort(fields[4], ':')
+		if !lok || !rok {
+			continue
+		}
+		c := gnet.ConnectionStat{
+			Family: addrFamily(laddr.IP),
+			Type:   syscall.SOCK_STREAM,
+			Laddr:  laddr,
+			Raddr:  raddr,
+			Status: fields[5],
+		}
+		if len(fields) > 6 {
+			pid, name, _ := strings.Cut(fields[6], "/")
+			if p, err := strconv.ParseInt(pid, 10, 32); err == nil {
+				c.Pid = int32(p)
+				if name != "" {
+					names[c.Pid] = name
+				}
+			}
+		}
+		conns = append(conns, c)
+	}
+	return conns, names
+}
+
+// parseNetstatWindows parses `netstat -ano` output (Windows):
+//
+//	TCP    0.0.0.0:135    0.0.0.0:0    LISTENING    1234
+//
+// IPv4 and IPv6 connections are both labelled TCP; IPv6 hosts are bracketed.
+// UDP lines are skipped.
+func parseNetstatWindows(out string) ([]gnet.ConnectionStat, procNames) {
+	var conns []gnet.ConnectionStat
+	sc := bufio.NewScanner(strings.NewReader(out))
+	for sc.Scan() {
+		fields := strings.Fields(sc.Text())
+		if len(fields) < 5 || fields[0] != "TCP" {
+			continue
+		}
+		laddr, lok := splitHostPort(fields[1], ':')
+		raddr, rok := splitHostPort(fields[2], ':')
+		if !lok || !rok {
+			continue
+		}
+		status := fields[3]
+		switch status {
+		case "LISTENING":
+			status = "LISTEN"
+		case "SYN_RECEIVED":
+			status = "SYN_RECV"
+		}
+		c := gnet.ConnectionStat{
+			Family: addrFamily(laddr.IP),
+			Type:   syscall.SOCK_STREAM,
+			Laddr:  laddr,
+			Raddr:  raddr,
+			Status: status,
+		}
+		if p, err := strconv.ParseInt(fields[4], 10, 32); err == nil {
+			c.Pid = int32(p)
+		}
+		conns = append(conns, c)
+	}
+	return conns, nil
+}
+
+// parseNetstatDarwin parses `netstat -anv -p tcp` output (macOS):
+//
+//	Proto Recv-Q Send-Q  Local Address   Foreign Address  (state)  ...  pid  ...
+//	tcp4       0      0  192.168.1.5.52345  17.57.144.5.5223  ESTABLISHED  ...  543  ...
+//
+// Addresses use host.port with the port after the last dot, and "*" for
+// wildcards. The PID column moves between macOS releases (and is
+// "process:pid" on newer ones), so it is located from the header line.
+func parseNetstatDarwin(out string) ([]gnet.ConnectionStat, procNames) {
+	var conns []gnet.ConnectionStat
+	names := make(procNames)
+	pidCol := -1
+	sc := bufio.NewScanner(strings.NewReader(out))
+	for sc.Scan() {
+		line := sc.Text()
+		if strings.HasPrefix(line, "Proto") {
+			line = strings.NewReplacer("Local Address", "Local-Address", "Foreign Address", "Foreign-Address").Replace(line)
+			for i, h := range strings.Fields(line) {
+				if h == "pid" || h == "process:pid" {
+					pidCol = i
+					break
+				}
+			}
+			continue
+		}
+		fields := strings.Fields(line)
+		if len(fields) < 6 || !strings.HasPrefix(fields[0], "tcp") {
+			continue
+		}
+		laddr, lok := splitHostPort(fields[3], '.')
+		raddr, rok := splitHostPort(fields[4], '.')
+		if !lok || !rok {
+			continue
+		}
+		family := uint32(syscall.AF_INET)
+		if fields[0] == "tcp6" || fields[0] == "tcp46" {
+			family = syscall.AF_INET6
+		}
+		c := gnet.ConnectionStat{
+			Family: family,
+			Type:   syscall.SOCK_STREAM,
+			Laddr:  laddr,
+			Raddr:  raddr,
+			Status: fields[5],
+		}
+		if pidCol >= 0 && pidCol < len(fields) {
+			pid, name := fields[pidCol], ""
+			if i := strings.LastIndexByte(pid, ':'); i >= 0 {
+				pid, name = pid[i+1:], pid[:i]
+			}
+			if p, err := strconv.ParseInt(pid, 10, 32); err == nil {
+				c.Pid = int32(p)
+				if name != "" {
+					names[c.Pid] = name
+				}
+			}
+		}
+		conns = append(conns, c)
+	}
+	return conns, names
+}
+
+// splitHostPort splits an address at the last sep. Brackets around IPv6
+// hosts are removed and "*" hosts or ports are treated as unset.
+func splitHostPort(s string, sep byte) (gnet.Addr, bool) {
+	i := strings.LastIndexByte(s, sep)
+	if i < 0 {
+		return gnet.Addr{}, false
+	}
+	host, port := s[:i], s[i+1:]
+	host = strings.TrimSuffix(strings.TrimPrefix(host, "["), "]")
+	if host == "*" {
+		host = ""
+	}
+
+	var a gnet.Addr
+	a.IP = host
+	if port != "*" {
+		p, err := strconv.ParseUint(port, 10, 16)
+		if err != nil {
+			return gnet.Addr{}, false
+		}
+		a.Port = uint32(p)
+	}
+	return a, true
+}
+
+func addrFamily(ip string) uint32 {
+	if strings.Contains(ip, ":") {
+		return syscall.AF_INET6
+	}
+	return syscall.AF_INET
+}
@@ -0,0 +1,112 @@
+package main
+
+import (
+	"fmt"
+	"maps"
+	"slices"
+	"syscall"
+	"testing"
+
+	gnet "github.com/shirou/gopsutil/v4/net"
+)
+
+// parsedConn is a parsed connection in a form that is easy to compare.
+func parsedConn(c gnet.ConnectionStat) string {
+	family := "inet"
+	if c.Family == syscall.AF_INET6 {
+		family = "inet6"
+	}
+	return fmt.Sprintf("%s %s:%d %s:%d %s %d", family, c.Laddr.IP, c.Laddr.Port, c.Raddr.IP, c.Raddr.Port, c.Status, c.Pid)
+}
+
+func TestBackendParsers(t *testing.T) {
+	tests := []struct {
+		name  string
+		parse func(string) ([]gnet.ConnectionStat, procNames)
+		out   string
+		want  []string
+		names procNames
+	}{
+		{"ss", parseSS, `State  Recv-Q Send-Q Local Address:Port  Peer Address:Port Process
+LISTEN 0      128    0.0.0.0:22          0.0.0.0:*         users:(("sshd",pid=123,fd=3))
+ESTAB  0      0      192.168.1.5:22      192.168.1.9:51234 users:(("sshd",pid=456,fd=4),("sshd",pid=457,fd=4))
+LISTEN 0      128    [::]:22             [::]:*            users:(("sshd",pid=123,fd=4))
+ESTAB  0      0      [fe80::1%eth0]:22   [fe80::9%eth0]:50000
+SYN-RECV 0    0      [2001:db8::1]:443   [2001:db8::9]:40000
+`, []string{
+			"inet 0.0.0.0:22 0.0.0.0:0 LISTEN 123",
+			"inet 192.168.1.5:22 192.168.1.9:51234 ESTABLISHED 456",
+			"inet6 :::22 :::0 LISTEN 123",
+			"inet6 fe80::1%eth0:22 fe80::9%eth0:50000 ESTABLISHED 0",
+			"inet6 2001:db8::1:443 2001:db8::9:40000 SYN_RECV 0",
+		}, procNames{123: "sshd", 456: "sshd"}},
+
+		{"netstat linux", parseNetstatLinux, `Active Internet connections (servers and established)
+Proto Recv-Q Send-Q Local Address           Foreign Address         State       PID/Program name
+tcp        0      0 0.0.0.0:22              0.0.0.0:*               LISTEN      123/sshd
+tcp        0      0 192.168.1.5:22          192.168.1.9:51234       ESTABLISHED -
+tcp6       0      0 :::80                   :::*                    LISTEN      789/nginx
+tcp6       0      0 2001:db8::1:443         2001:db8::9:40000       TIME_WAIT   -
+udp        0      0 0.0.0.0:68              0.0.0.0:*                           1/systemd
+`, []string{
+			"inet 0.0.0.0:22 0.0.0.0:0 LISTEN 123",
+			"inet 192.168.1.5:22 192.168.1.9:51234 ESTABLISHED 0",
+			"inet6 :::80 :::0 LISTEN 789",
+			"inet6 2001:db8::1:443 2001:db8::9:40000 TIME_WAIT 0",
+		}, procNames{123: "sshd", 789: "nginx"}},
+
+		{"netstat windows", parseNetstatWindows, `
+Active Connections
+
+  Proto  Local Address          Foreign Address        State           PID
+  TCP    0.0.0.0:135            0.0.0.0:0              LISTENING       1234
+  TCP    192.168.1.5:49700      20.42.65.92:443        ESTABLISHED     5678
+  TCP    [::]:135               [::]:0                 LISTENING       1234
+  TCP    [::1]:49664            [::1]:49665            SYN_RECEIVED    42
+  UDP    0.0.0.0:5353           *:*                                    999
+`, []string{
+			"inet 0.0.0.0:135 0.0.0.0:0 LISTEN 1234",
+			"inet 192.168.1.5:49700 20.42.65.92:443 ESTABLISHED 5678",
+			"inet6 :::135 :::0 LISTEN 1234",
+			"inet6 ::1:49664 ::1:49665 SYN_RECV 42",
+		}, nil},
+
+		{"netstat darwin", parseNetstatDarwin, `Active Internet connections (including servers)
+Proto Recv-Q Send-Q  Local Address          Foreign Address        (state)      rhiwat shiwat    pid   epid  state    options
+tcp4       0      0  192.168.1.5.52345      17.57.144.5.5223       ESTABLISHED  131072 131768    543      0 0x0102 0x00000008
+tcp46      0      0  *.8080                 *.*                    LISTEN       131072 131072    777      0 0x0100 0x00000006
+tcp6       0      0  ::1.631                ::1.50000              ESTABLISHED  131072 131072    88       0 0x0102 0x00000000
+`, []string{
+			"inet 192.168.1.5:52345 17.57.144.5:5223 ESTABLISHED 543",
+			"inet6 :8080 :0 LISTEN 777",
+			"inet6 ::1:631 ::1:50000 ESTABLISHED 88",
+		}, procNames{}},
+
+		{"netstat darwin process:pid", parseNetstatDarwin, `Active Internet connections (including servers)
+Proto Recv-Q Send-Q  Local Address          Foreign Address        (state)       rxbytes      txbytes  rhiwat  shiwat    process:pid  state  options
+tcp4       0      0  127.0.0.1.5432         *.*                    LISTEN              0            0  131072  131072  postgres:312   00000  00000006
+tcp6       0      0  fe80::1%lo0.631        *.*                    LISTEN              0            0  131072  131072  cupsd:90       00000  00000006
+`, []string{
+			"inet 127.0.0.1:5432 :0 LISTEN 312",
+			"inet6 fe80::1%lo0:631 :0 LISTEN 90",
+		}, procNames{312: "postgres", 90: "cupsd"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			conns, names := tt.parse(tt.out)
+			var got []string
+			for _, c := range conns {
+				if c.Type != syscall.SOCK_STREAM {
+					t.Errorf("%s: type %d, want SOCK_STREAM", parsedConn(c), c.Type)
+				}
+				got = append(got, parsedConn(c))
+			}
+			if !slices.Equal(got, tt.want) {
+				t.Errorf("got\n%q\nwant\n%q", got, tt.want)
+			}
+			if !maps.Equal(names, tt.names) {
+				t.Errorf("names = %v, want %v", names, tt.names)
+			}
+		})
+	}
+}
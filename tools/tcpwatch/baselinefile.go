@@ -0,0 +1,85 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"time"
+
+	"github.com/bulent/morzer/tools/tcpwatch/internal/render"
+)
+
+// fileBaseline is a saved snapshot that -watch-compare-baseline-file diffs
+// every refresh against.
+type fileBaseline struct {
+	updated time.Time
+	rows    map[connKey]render.Row
+}
+
+// loadBaselineFile reads a snapshot written by -snapshot-file or
+// -flush-on-signal (or the row array printed by -json). Its rows are
+// relabelled with host so they compare equal to this machine's rows
+// whatever -host-label was used when it was taken.
+func loadBaselineFile(path, host string, id identity) (*fileBaseline, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("-watch-compare-baseline-file: %w", err)
+	}
+	var snap jsonSnapshot
+	if err := json.Unmarshal(data, &snap); err != nil {
+		if err := json.Unmarshal(data, &snap.Rows); err != nil {
+			return nil, fmt.Errorf("-watch-compare-baseline-file: %s is neither a snapshot nor a -json row array", path)
+		}
+	}
+	for i := range snap.Rows {
+		snap.Rows[i].Host = host
+	}
+	return &fileBaseline{updated: snap.Updated, rows: rowsByKey(snap.Rows, id)}, nil
+}
+
+// printBaselineDiff prints how rows differ from the saved baseline: added and
+// removed connections and state changes, using the -events notation. With
+// -jsonl each difference is one event object.
+func printBaselineDiff(w io.Writer, opts options, st *watchState, rows []render.Row) error {
+	now := time.Now()
+	// Saved snapshots don't carry process start times, so connections are
+	// matched without them.
+	cur := make([]render.Row, len(rows))
+	for i, r := range rows {
+		r.Started = 0
+		cur[i] = r
+	}
+	events := diffRows(st.fileBaseline.rows, cur, opts.identity, now)
+
+	if opts.jsonLines {
+		enc := json.NewEncoder(w)
+		for _, ev := range events {
+			if err := enc.Encode(ev); err != nil {
+				return err
+			}
+		}
+		return nil
+	}
+
+	if !opts.noClear {
+		clearScreen(w, opts, st)
+	}
+	counts := make(map[string]int)
+	for _, ev := range events {
+		counts[ev.Kind]++
+	}
+	taken := "unknown time"
+	if !st.fileBaseline.updated.IsZero() {
+		taken = st.fileBaseline.updated.Format(time.RFC3339)
+	}
+	fmt.Fprintf(w, "Compared with baseline from %s\n", taken)
+	fmt.Fprintf(w, "Updated:  %s  %d added, %d removed, %d changed\n", now.Format(time.RFC3339),
+		counts[eventAdded], counts[eventRemoved], counts[eventStateChanged])
+	for _, ev := range events {
+		if _, err := fmt.Fprintln(w, eventMessage(ev)); err != nil {
+			return err
+		}
+	}
+	return nil
+}
@@ -0,0 +1,41 @@
+package main
+
+import (
+	"net/netip"
+	"strings"
+
+	"github.com/bulent/morzer/tools/tcpwatch/internal/render"
+)
+
+// flagBusyRemotes marks the rows whose remote host appears in more than limit
+// rows, which usually means a scan or a connection leak.
+func flagBusyRemotes(rows []render.Row, limit int) {
+	counts := make(map[string]int)
+	for _, r := range rows {
+		if h := remoteHost(r.Remote); h != "" {
+			counts[h]++
+		}
+	}
+	for i, r := range rows {
+		if h := remoteHost(r.Remote); h != "" && counts[h] > limit {
+			rows[i].Flagged = true
+		}
+	}
+}
+
+// remoteHost returns the host of a formatted remote address, or "" when the
+// remote end is unset (listeners).
+func remoteHost(addr string) string {
+	i := strings.LastIndexByte(addr, ':')
+	if i < 0 {
+		return ""
+	}
+	host := strings.TrimSuffix(strings.TrimPrefix(addr[:i], "["), "]")
+	if host == "*" || host == "" {
+		return ""
+	}
+	if ip, err := netip.ParseAddr(host); err == nil && ip.IsUnspecified() {
+		return ""
+	}
+	return host
+}
@@ -0,0 +1,107 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"net"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/bulent/morzer/tools/tcpwatch/internal/render"
+)
+
+// cidrBits are the -group-cidr prefix lengths for each address family.
+type cidrBits struct {
+	v4, v6 int
+}
+
+// parseCIDRBits parses "/V4[,/V6]" (the slashes are optional). The IPv6
+// length defaults to 64, the usual size of a single network.
+func parseCIDRBits(s string) (*cidrBits, error) {
+	v4s, v6s, hasV6 := strings.Cut(s, ",")
+	bits := &cidrBits{v6: 64}
+	var err error
+	if bits.v4, err = prefixLen(v4s, 32); err != nil {
+		return nil, err
+	}
+	if hasV6 {
+		if bits.v6, err = prefixLen(v6s, 128); err != nil {
+			return nil, err
+		}
+	}
+	return bits, nil
+}
+
+func prefixLen(s string, max int) (int, error) {
+	s = strings.TrimPrefix(strings.TrimSpace(s), "/")
+	n, err := strconv.Atoi(s)
+	if err != nil || n < 0 || n > max {
+		return 0, fmt.Errorf("invalid -group-cidr prefix length %q (want /0 to /%d)", s, max)
+	}
+	return n, nil
+}
+
+// subnet returns the network ip belongs to in CIDR notation, or "" if ip
+// isn't an IP address (e.g. under -redact).
+func (b cidrBits) subnet(ip string) string {
+	parsed := net.ParseIP(ip)
+	if parsed == nil {
+		return ""
+	}
+	mask := net.CIDRMask(b.v6, 128)
+	if v4 := parsed.To4(); v4 != nil {
+		parsed, mask = v4, net.CIDRMask(b.v4, 32)
+	}
+	return (&net.IPNet{IP: parsed.Mask(mask), Mask: mask}).String()
+}
+
+// subnetCounts is the -group-cidr report for one refresh.
+type subnetCounts struct {
+	Updated time.Time     `json:"updated"`
+	Subnets []subnetCount `json:"subnets"`
+}
+
+type subnetCount struct {
+	Subnet string `json:"subnet"`
+	Count  int    `json:"count"`
+}
+
+// printSubnetCounts prints how many connections go to each remote subnet,
+// busiest first. Listeners have no remote end and aren't counted.
+func printSubnetCounts(w io.Writer, opts options, rows []render.Row) error {
+	now := time.Now()
+	counts := make(map[string]int)
+	for _, r := range rows {
+		if subnet := opts.groupCIDR.subnet(remoteHost(r.Remote)); subnet != "" {
+			counts[subnet]++
+		}
+	}
+	report := subnetCounts{Updated: now, Subnets: []subnetCount{}}
+	for subnet, n := range counts {
+		report.Subnets = append(report.Subnets, subnetCount{Subnet: subnet, Count: n})
+	}
+	sort.Slice(report.Subnets, func(i, j int) bool {
+		a, b := report.Subnets[i], report.Subnets[j]
+		if a.Count != b.Count {
+			return a.Count > b.Count
+		}
+		return a.Subnet < b.Subnet
+	})
+
+	if opts.jsonLines || opts.jsonOut {
+		enc := json.NewEncoder(w)
+		if opts.jsonOut {
+			enc = jsonOutEncoder(w, opts)
+		}
+		return enc.Encode(report)
+	}
+
+	ts := now.Format(time.RFC3339)
+	for _, s := range report.Subnets {
+		fmt.Fprintf(w, "%s %s %d\n", ts, s.Subnet, s.Count)
+	}
+	return nil
+}
@@ -0,0 +1,56 @@
+package main
+
+import "testing"
+
+func TestParseCIDRBits(t *testing.T) {
+	tests := []struct {
+		arg     string
+		want    cidrBits
+		wantErr bool
+	}{
+		{arg: "/24", want: cidrBits{24, 64}},
+		{arg: "24", want: cidrBits{24, 64}},
+		{arg: "/24,/48", want: cidrBits{24, 48}},
+		{arg: " /16 , 56 ", want: cidrBits{16, 56}},
+		{arg: "/0,/0", want: cidrBits{0, 0}},
+		{arg: "/32,/128", want: cidrBits{32, 128}},
+		{arg: "/33", wantErr: true},
+		{arg: "/24,/129", wantErr: true},
+		{arg: "/-1", wantErr: true},
+		{arg: "", wantErr: true},
+		{arg: "/24,", wantErr: true},
+		{arg: "abc", wantErr: true},
+	}
+	for _, tt := range tests {
+		got, err := parseCIDRBits(tt.arg)
+		if tt.wantErr {
+			if err == nil {
+				t.Errorf("parseCIDRBits(%q) = %+v, want an error", tt.arg, *got)
+			}
+			continue
+		}
+		if err != nil || *got != tt.want {
+			t.Errorf("parseCIDRBits(%q) = %v, %v; want %+v", tt.arg, got, err, tt.want)
+		}
+	}
+}
+
+func TestCIDRSubnet(t *testing.T) {
+	b := cidrBits{v4: 24, v6: 48}
+	tests := []struct {
+		ip   string
+		want string
+	}{
+		{"192.0.2.77", "192.0.2.0/24"},
+		{"2001:db8:1:2::77", "2001:db8:1::/48"},
+		{"::ffff:192.0.2.77", "192.0.2.0/24"}, // IPv4-mapped counts as IPv4
+		{"10.x.x.x", ""},                      // redacted
+		{"ip-1a2b3c4d", ""},                   // redacted
+		{"", ""},
+	}
+	for _, tt := range tests {
+		if got := b.subnet(tt.ip); got != tt.want {
+			t.Errorf("subnet(%q) = %q, want %q", tt.ip, got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,56 @@
+package main
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"time"
+)
+
+// clearScreen starts a table refresh at the top of the screen. Within
+// -clear-rate of the last full clear the cursor is only moved home and the
+// previous output is overwritten in place, which flickers less.
+func clearScreen(w io.Writer, opts options, st *watchState) {
+	now := time.Now()
+	if opts.clearRate > 0 && !st.lastClear.IsZero() && now.Sub(st.lastClear) < opts.clearRate {
+		fmt.Fprint(w, "\033[H")
+		st.inPlace = true
+		return
+	}
+	fmt.Fprint(w, "\033[2J\033[H")
+	st.lastClear = now
+}
+
+// startRefresh returns the writer a refresh prints to. With -clear-rate,
+// output drawn in place (see clearScreen) erases the rest of every line it
+// writes; endRefresh then erases what's left below it.
+func (st *watchState) startRefresh(w io.Writer, opts options) io.Writer {
+	st.inPlace = false
+	if opts.clearRate <= 0 {
+		return w
+	}
+	return eraseLineWriter{w: w, st: st}
+}
+
+func (st *watchState) endRefresh(w io.Writer) {
+	if st.inPlace {
+		fmt.Fprint(w, "\033[J")
+	}
+}
+
+// eraseLineWriter erases to the end of the line before every newline while
+// a refresh is drawn in place.
+type eraseLineWriter struct {
+	w  io.Writer
+	st *watchState
+}
+
+func (e eraseLineWriter) Write(p []byte) (int, error) {
+	if !e.st.inPlace {
+		return e.w.Write(p)
+	}
+	if _, err := e.w.Write(bytes.ReplaceAll(p, []byte("\n"), []byte("\033[K\n"))); err != nil {
+		return 0, err
+	}
+	return len(p), nil
+}
@@ -0,0 +1,27 @@
+//go:build darwin
+
+package main
+
+import (
+	"bytes"
+	"context"
+	"os/exec"
+)
+
+const codesignSupported = true
+
+// unsignedExe reports whether the binary at path is unsigned or only ad-hoc
+// signed, according to codesign(1). Ad-hoc signatures carry no identity, so
+// for -unsigned-only they are as anonymous as no signature at all.
+func unsignedExe(ctx context.Context, path string) (bool, error) {
+	// codesign -d prints the signature details on stderr and exits non-zero
+	// for unsigned code.
+	out, err := exec.CommandContext(ctx, "codesign", "-d", "--verbose=2", path).CombinedOutput()
+	if bytes.Contains(out, []byte("not signed at all")) {
+		return true, nil
+	}
+	if err != nil {
+		return false, err
+	}
+	return bytes.Contains(out, []byte("Signature=adhoc")), nil
+}
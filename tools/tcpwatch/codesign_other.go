@@ -0,0 +1,17 @@
+//go:build !darwin
+
+package main
+
+import (
+	"context"
+	"fmt"
+	"runtime"
+)
+
+// Code signature checks use macOS's codesign; -unsigned-only is ignored
+// elsewhere.
+const codesignSupported = false
+
+func unsignedExe(ctx context.Context, path string) (bool, error) {
+	return false, fmt.Errorf("code signature checks not available on %s", runtime.GOOS)
+}
@@ -0,0 +1,92 @@
+package main
+
+import (
+	"net"
+	"net/netip"
+	"strconv"
+
+	"github.com/bulent/morzer/tools/tcpwatch/internal/render"
+)
+
+// dedupLinkLocal collapses LISTEN rows for the same IPv6 link-local address
+// that differ only by zone (one per interface, e.g. fe80::1%en0 and
+// fe80::1%en1) into a single row whose zone is shown as "%*".
+func dedupLinkLocal(rows []render.Row) []render.Row {
+	type key struct {
+		proto string
+		addr  netip.AddrPort
+		pid   int32
+	}
+	first := make(map[key]int)
+	out := rows[:0]
+	for _, r := range rows {
+		ap, ok := zonedListener(r)
+		if !ok {
+			out = append(out, r)
+			continue
+		}
+		k := key{proto: r.Proto, addr: netip.AddrPortFrom(ap.Addr().WithZone(""), ap.Port()), pid: r.PID}
+		if i, seen := first[k]; seen {
+			out[i].Local = net.JoinHostPort(k.addr.Addr().String()+"%*", strconv.Itoa(int(ap.Port())))
+			continue
+		}
+		first[k] = len(out)
+		out = append(out, r)
+	}
+	return out
+}
+
+func zonedListener(r render.Row) (netip.AddrPort, bool) {
+	if !r.Listening {
+		return netip.AddrPort{}, false
+	}
+	ap, err := netip.ParseAddrPort(r.Local)
+	if err != nil || ap.Addr().Zone() == "" || !ap.Addr().IsLinkLocalUnicast() {
+		return netip.AddrPort{}, false
+	}
+	return ap, true
+}
+
+// coalesceListeners collapses a dual-stack service's pair of wildcard LISTEN
+// rows (0.0.0.0:443 and [::]:443, same process) into one row listening on
+// "*:443" with proto "tcp46". Rows without a known owner (PID 0) may belong
+// to different processes and are left alone.
+func coalesceListeners(rows []render.Row) []render.Row {
+	type key struct {
+		port uint16
+		pid  int32
+	}
+	first := make(map[key]int)
+	out := rows[:0]
+	for _, r := range rows {
+		ap, ok := wildcardListener(r)
+		if !ok || r.PID <= 0 {
+			out = append(out, r)
+			continue
+		}
+		k := key{port: ap.Port(), pid: r.PID}
+		if i, seen := first[k]; seen && out[i].Proto != r.Proto {
+			out[i].Proto = "tcp46"
+			out[i].Local = "*:" + strconv.Itoa(int(ap.Port()))
+			if out[i].Family != "" {
+				out[i].Family = "inet+inet6"
+			}
+			delete(first, k)
+			continue
+		}
+		first[k] = len(out)
+		out = append(out, r)
+	}
+	return out
+}
+
+func wildcardListener(r render.Row) (netip.AddrPort, bool) {
+	if !r.Listening || (r.Proto != "tcp4" && r.Proto != "tcp6") {
+		return netip.AddrPort{}, false
+	}
+	ap, err := netip.ParseAddrPort(r.Local)
+	if err != nil || !ap.Addr().IsUnspecified() {
+		return netip.AddrPort{}, false
+	}
+	return ap, true
+}
@@ -0,0 +1,75 @@
+package main
+
+import (
+	"slices"
+	"testing"
+
+	"github.com/bulent/morzer/tools/tcpwatch/internal/render"
+)
+
+func TestDedupLinkLocal(t *testing.T) {
+	listen := func(local string, pid int32) render.Row {
+		return render.Row{Proto: "tcp6", Local: local, Remote: "[::]:0", State: "LISTEN", PID: pid, Listening: true}
+	}
+	rows := []render.Row{
+		listen("[fe80::1%eth0]:22", 7),
+		listen("[fe80::1%eth1]:22", 7),
+		listen("[fe80::1%eth2]:22", 7),
+		listen("[fe80::1%eth0]:8080", 7), // another port: kept
+		listen("[fe80::1%eth0]:53", 9),   // a single zone: left as is
+		listen("[fe80::1%eth1]:53", 10),  // same address, another process: kept
+		listen("[fe80::2%eth0]:22", 7),   // another address: kept
+		listen("[2001:db8::1]:22", 7),    // not link-local
+		{Proto: "tcp6", Local: "[fe80::1%eth0]:22", Remote: "[fe80::9%eth0]:50000", State: "ESTABLISHED", PID: 7},
+		{Proto: "tcp6", Local: "[fe80::1%eth1]:22", Remote: "[fe80::9%eth1]:50000", State: "ESTABLISHED", PID: 7},
+	}
+	var got []string
+	for _, r := range dedupLinkLocal(rows) {
+		got = append(got, r.State+" "+r.Local)
+	}
+	want := []string{
+		"LISTEN [fe80::1%*]:22",
+		"LISTEN [fe80::1%eth0]:8080",
+		"LISTEN [fe80::1%eth0]:53",
+		"LISTEN [fe80::1%eth1]:53",
+		"LISTEN [fe80::2%eth0]:22",
+		"LISTEN [2001:db8::1]:22",
+		"ESTABLISHED [fe80::1%eth0]:22",
+		"ESTABLISHED [fe80::1%eth1]:22",
+	}
+	if !slices.Equal(got, want) {
+		t.Errorf("got\n%q\nwant\n%q", got, want)
+	}
+}
+
+func TestCoalesceListeners(t *testing.T) {
+	listen := func(proto, local string, pid int32) render.Row {
+		return render.Row{Proto: proto, Local: local, State: "LISTEN", PID: pid, Listening: true}
+	}
+	rows := []render.Row{
+		listen("tcp4", "0.0.0.0:443", 7),
+		listen("tcp6", "[::]:443", 7),
+		listen("tcp4", "0.0.0.0:80", 8),
+		listen("tcp6", "[::]:80", 9), // another process: kept
+		listen("tcp4", "0.0.0.0:22", 0),
+		listen("tcp6", "[::]:22", 0), // owner unknown: kept
+		listen("tcp4", "127.0.0.1:53", 10),
+		listen("tcp6", "[::1]:53", 10), // not wildcards: kept
+	}
+	var got []string
+	for _, r := range coalesceListeners(rows) {
+		got = append(got, r.Proto+" "+r.Local)
+	}
+	want := []string{
+		"tcp46 *:443",
+		"tcp4 0.0.0.0:80",
+		"tcp6 [::]:80",
+		"tcp4 0.0.0.0:22",
+		"tcp6 [::]:22",
+		"tcp4 127.0.0.1:53",
+		"tcp6 [::1]:53",
+	}
+	if !slices.Equal(got, want) {
+		t.Errorf("got\n%q\nwant\n%q", got, want)
+	}
+}
@@ -0,0 +1,82 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"os"
+	"runtime"
+	"runtime/debug"
+
+	gnet "github.com/shirou/gopsutil/v4/net"
+)
+
+// diagSampleSize is how many raw connections -diag includes.
+const diagSampleSize = 5
+
+// diagReport is what -diag prints for bug reports.
+type diagReport struct {
+	Platform    string          `json:"platform"`
+	Supported   bool            `json:"supported"`
+	GOOS        string          `json:"goos"`
+	GOARCH      string          `json:"goarch"`
+	GoVersion   string          `json:"go_version"`
+	Gopsutil    string          `json:"gopsutil_version"`
+	Connections diagConnections `json:"connections"`
+	PsComm      diagPsComm      `json:"ps_comm"`
+}
+
+type diagConnections struct {
+	OK     bool                  `json:"ok"`
+	Error  string                `json:"error,omitempty"`
+	Count  int                   `json:"count"`
+	Sample []gnet.ConnectionStat `json:"sample"`
+}
+
+type diagPsComm struct {
+	PID   int    `json:"pid"`
+	Name  string `json:"name,omitempty"`
+	Error string `json:"error,omitempty"`
+}
+
+// printDiag prints platform details as JSON: what tcpwatch was built with,
+// whether gopsutil can list connections (with a few raw values) and whether
+// the ps name fallback works for tcpwatch's own PID.
+func printDiag(ctx context.Context, w io.Writer) error {
+	r := diagReport{
+		Platform:  platformName(),
+		Supported: platformSupported,
+		GOOS:      runtime.GOOS,
+		GOARCH:    runtime.GOARCH,
+		GoVersion: runtime.Version(),
+		Gopsutil:  "unknown",
+	}
+	if info, ok := debug.ReadBuildInfo(); ok {
+		for _, dep := range info.Deps {
+			if dep.Path == "github.com/shirou/gopsutil/v4" {
+				r.Gopsutil = dep.Version
+			}
+		}
+	}
+
+	conns, err := gnet.ConnectionsWithContext(ctx, "tcp")
+	r.Connections.Sample = []gnet.ConnectionStat{}
+	if err != nil {
+		r.Connections.Error = err.Error()
+	} else {
+		r.Connections.OK = true
+		r.Connections.Count = len(conns)
+		r.Connections.Sample = conns[:min(len(conns), diagSampleSize)]
+	}
+
+	r.PsComm.PID = os.Getpid()
+	if name, err := psComm(ctx, int32(r.PsComm.PID)); err != nil {
+		r.PsComm.Error = err.Error()
+	} else {
+		r.PsComm.Name = name
+	}
+
+	enc := json.NewEncoder(w)
+	enc.SetIndent("", "  ")
+	return enc.Encode(r)
+}
@@ -0,0 +1,60 @@
+package main
+
+import (
+	"context"
+	"net"
+	"strings"
+	"time"
+)
+
+// dnsCache resolves remote IPs to PTR names for -resolve. Answers, including
+// failures, are cached so a refresh only waits for addresses it hasn't seen
+// recently.
+type dnsCache struct {
+	timeout time.Duration
+	ttl     time.Duration
+	max     int
+	entries map[string]dnsEntry
+}
+
+type dnsEntry struct {
+	name  string
+	until time.Time
+}
+
+func newDNSCache() *dnsCache {
+	return &dnsCache{
+		timeout: time.Second,
+		ttl:     5 * time.Minute,
+		max:     4096,
+		entries: make(map[string]dnsEntry),
+	}
+}
+
+// Name returns the first PTR name of ip without the trailing dot, or "" if
+// it has none or the lookup times out.
+func (d *dnsCache) Name(ctx context.Context, ip string) string {
+	now := time.Now()
+	if e, ok := d.entries[ip]; ok && now.Before(e.until) {
+		return e.name
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, d.timeout)
+	defer cancel()
+	name := ""
+	if names, err := net.DefaultResolver.LookupAddr(ctx, ip); err == nil && len(names) > 0 {
+		name = strings.TrimSuffix(names[0], ".")
+	}
+
+	if len(d.entries) >= d.max {
+		for k, e := range d.entries {
+			if now.After(e.until) {
+				delete(d.entries, k)
+			}
+		}
+	}
+	if len(d.entries) < d.max {
+		d.entries[ip] = dnsEntry{name: name, until: now.Add(d.ttl)}
+	}
+	return name
+}
@@ -0,0 +1,13 @@
+//go:build !windows
+
+package main
+
+import (
+	"os"
+	"syscall"
+)
+
+// dumpSignals are the signals that make -flush-on-signal write a snapshot.
+func dumpSignals() []os.Signal {
+	return []os.Signal{syscall.SIGUSR2}
+}
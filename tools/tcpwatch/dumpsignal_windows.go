@@ -0,0 +1,10 @@
+//go:build windows
+
+package main
+
+import "os"
+
+// Windows has no SIGUSR2, so -flush-on-signal does nothing there.
+func dumpSignals() []os.Signal {
+	return nil
+}
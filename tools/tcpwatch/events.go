@@ -0,0 +1,177 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"sort"
+	"time"
+
+	"github.com/bulent/morzer/tools/tcpwatch/internal/render"
+)
+
+// Event kinds emitted by -events.
+const (
+	eventAdded        = "added"
+	eventRemoved      = "removed"
+	eventStateChanged = "state-changed"
+)
+
+// connKey identifies a connection across refreshes (see identity.key). State is deliberately
+// not part of it so state transitions show up as changes of one connection.
+// started (the process start time, when known) keeps a reused PID from
+// passing for the process that had it before.
+type connKey struct {
+	host    string
+	proto   string
+	local   string
+	remote  string
+	pid     int32
+	started int64
+}
+
+type connEvent struct {
+	Updated   time.Time  `json:"updated"`
+	Kind      string     `json:"event"`
+	Row       render.Row `json:"row"`
+	PrevState string     `json:"prev_state,omitempty"`
+}
+
+// diffRows returns the events that turn prev into cur, ordered by kind
+// (added, removed, state-changed) and then by connection. Rows with the same
+// identity count once, as the row rowsByKey picks for them.
+func diffRows(prev map[connKey]render.Row, cur []render.Row, id identity, now time.Time) []connEvent {
+	var events []connEvent
+	byKey := rowsByKey(cur, id)
+	seen := make(map[connKey]struct{}, len(byKey))
+	for _, r := range cur {
+		k := id.key(r)
+		if _, dup := seen[k]; dup {
+			continue
+		}
+		seen[k] = struct{}{}
+		r = byKey[k]
+		old, ok := prev[k]
+		switch {
+		case !ok:
+			events = append(events, connEvent{Updated: now, Kind: eventAdded, Row: r})
+		case old.State != r.State:
+			events = append(events, connEvent{Updated: now, Kind: eventStateChanged, Row: r, PrevState: old.State})
+		}
+	}
+	for k, r := range prev {
+		if _, ok := seen[k]; !ok {
+			events = append(events, connEvent{Updated: now, Kind: eventRemoved, Row: r})
+		}
+	}
+
+	order := map[string]int{eventAdded: 0, eventRemoved: 1, eventStateChanged: 2}
+	sort.Slice(events, func(i, j int) bool {
+		a, b := events[i], events[j]
+		if a.Kind != b.Kind {
+			return order[a.Kind] < order[b.Kind]
+		}
+		if a.Row.Local != b.Row.Local {
+			return a.Row.Local < b.Row.Local
+		}
+		if a.Row.Remote != b.Row.Remote {
+			return a.Row.Remote < b.Row.Remote
+		}
+		return a.Row.PID < b.Row.PID
+	})
+	return events
+}
+
+// rowsByKey indexes rows by identity. When a coarse -identity gives several
+// rows the same key, the one whose state sorts first stands for them all, so
+// the pick doesn't depend on the order the backend listed the rows in.
+func rowsByKey(rows []render.Row, id identity) map[connKey]render.Row {
+	m := make(map[connKey]render.Row, len(rows))
+	for _, r := range rows {
+		k := id.key(r)
+		if old, ok := m[k]; ok && old.State <= r.State {
+			continue
+		}
+		m[k] = r
+	}
+	return m
+}
+
+// eventSink receives each refresh's events in addition to the printed
+// output.
+type eventSink interface {
+	send(events []connEvent) error
+}
+
+// printEvents prints what changed since the previous refresh and hands the
+// events to st.sinks. The first refresh only records a baseline.
+func printEvents(w io.Writer, opts options, st *watchState, rows []render.Row) error {
+	now := time.Now()
+	cur := rowsByKey(rows, opts.identity)
+	if !st.primed {
+		st.prev, st.primed = cur, true
+		return nil
+	}
+	events := diffRows(st.prev, rows, opts.identity, now)
+	st.prev = cur
+
+	if opts.onlyStateChanges {
+		changed := events[:0]
+		for _, ev := range events {
+			if ev.Kind == eventStateChanged {
+				changed = append(changed, ev)
+			}
+		}
+		events = changed
+	}
+
+	if len(events) > 0 {
+		for _, sink := range st.sinks {
+			if err := sink.send(events); err != nil {
+				fmt.Fprintln(os.Stderr, err)
+			}
+		}
+	}
+
+	enc := json.NewEncoder(w)
+	for _, ev := range events {
+		if opts.jsonLines {
+			if err := enc.Encode(ev); err != nil {
+				return err
+			}
+			continue
+		}
+		if _, err := fmt.Fprintln(w, formatEvent(ev)); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func formatEvent(ev connEvent) string {
+	return ev.Updated.Format(time.RFC3339) + " " + eventMessage(ev)
+}
+
+// eventMessage describes an event without its timestamp.
+func eventMessage(ev connEvent) string {
+	r := ev.Row
+	sym, state := "+", r.State
+	switch ev.Kind {
+	case eventRemoved:
+		sym = "-"
+	case eventStateChanged:
+		sym, state = "~", ev.PrevState+"->"+r.State
+	}
+	process := r.Process
+	if process == "" {
+		process = "-"
+	}
+	switch ev.Kind {
+	case eventNewListener:
+		return fmt.Sprintf("! new listener %s %s pid=%d %s", r.Proto, r.Local, r.PID, process)
+	case eventRemotePort:
+		return fmt.Sprintf("! remote port %s %s -> %s %s pid=%d %s", r.Proto, r.Local, r.Remote, r.State, r.PID, process)
+	}
+	return fmt.Sprintf("%s %s %s -> %s %s pid=%d %s", sym, r.Proto, r.Local, r.Remote, state, r.PID, process)
+}
@@ -0,0 +1,25 @@
+package main
+
+import (
+	"syscall"
+	"testing"
+)
+
+// The address family numbers differ between platforms (AF_INET6 is 10 on
+// Linux, 30 on macOS and 23 on Windows), so they come from syscall.
+func TestFamilyProto(t *testing.T) {
+	tests := []struct {
+		family uint32
+		want   string
+	}{
+		{syscall.AF_INET, "tcp4"},
+		{syscall.AF_INET6, "tcp6"},
+		{syscall.AF_UNIX, "unix"},
+		{0, "tcp"}, // unknown
+	}
+	for _, tt := range tests {
+		if got := familyProto(tt.family); got != tt.want {
+			t.Errorf("familyProto(%d) = %q, want %q", tt.family, got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,64 @@
+package main
+
+import (
+	"sort"
+	"strings"
+)
+
+// snapshotFilters records the filters a snapshot was taken with, so a saved
+// capture says how it was produced.
+type snapshotFilters struct {
+	States       []string `json:"states,omitempty"`
+	PID          *int32   `json:"pid,omitempty"`
+	Port         int      `json:"port,omitempty"`
+	Proc         string   `json:"proc,omitempty"`
+	MatchField   string   `json:"match_field,omitempty"`
+	Listen       bool     `json:"listen"`
+	Direction    string   `json:"direction,omitempty"`
+	NoLoopback   bool     `json:"no_loopback,omitempty"`
+	LoopbackOnly bool     `json:"loopback_only,omitempty"`
+	NoUnknown    bool     `json:"no_unknown,omitempty"`
+	UnsignedOnly bool     `json:"unsigned_only,omitempty"`
+	Proto        string   `json:"proto,omitempty"`
+	Backend      string   `json:"backend,omitempty"`
+	SSH          string   `json:"ssh,omitempty"`
+	Replay       string   `json:"replay,omitempty"`
+	IgnoreFile   string   `json:"ignore_file,omitempty"`
+	// Sample is the -sample fraction when rows were sampled.
+	Sample float64 `json:"sample,omitempty"`
+}
+
+// filtersOf returns the effective filters of opts. Direction "all" only
+// classifies rows, so it isn't recorded.
+func filtersOf(opts options) *snapshotFilters {
+	f := &snapshotFilters{
+		Port:         opts.portFilter,
+		Proc:         strings.Join(opts.procFilters, ","),
+		Listen:       opts.listen,
+		NoLoopback:   opts.noLoopback,
+		LoopbackOnly: opts.loopbackOnly,
+		NoUnknown:    opts.noUnknown,
+		UnsignedOnly: opts.unsignedOnly && codesignSupported,
+		Proto:        opts.proto,
+		Backend:      opts.backend,
+		SSH:          opts.sshTarget,
+		Replay:       opts.replayFile,
+		IgnoreFile:   opts.ignoreFile,
+		Sample:       opts.sample,
+	}
+	for s := range opts.stateAllow {
+		f.States = append(f.States, s)
+	}
+	sort.Strings(f.States)
+	if opts.pidFilter >= 0 {
+		pid := opts.pidFilter
+		f.PID = &pid
+	}
+	if len(opts.procFilters) > 0 {
+		f.MatchField = opts.matchField
+	}
+	if opts.direction != "all" {
+		f.Direction = opts.direction
+	}
+	return f
+}
@@ -0,0 +1,68 @@
+package main
+
+import (
+	"fmt"
+	"slices"
+	"strings"
+)
+
+// Output formats accepted by -format. The older -json, -jsonl, -csv and
+// -influx flags are deprecated shorthands for the matching format.
+const (
+	formatTable      = "table"
+	formatJSON       = "json"
+	formatJSONL      = "jsonl"
+	formatCSV        = "csv"
+	formatTSV        = "tsv"
+	formatInflux     = "influx"
+	formatPrometheus = "prometheus"
+)
+
+var formats = []string{formatTable, formatJSON, formatJSONL, formatCSV, formatTSV, formatInflux, formatPrometheus}
+
+// resolveFormat reconciles -format with the shorthand flags, rejecting
+// conflicting choices, and sets opts.format and the shorthand booleans so
+// both describe the chosen format.
+func resolveFormat(opts *options, format string) error {
+	var shorthands []string
+	for _, f := range []struct {
+		set  bool
+		name string
+	}{{opts.jsonOut, formatJSON}, {opts.jsonLines, formatJSONL}, {opts.csv, formatCSV}, {opts.influx, formatInflux}} {
+		if f.set {
+			shorthands = append(shorthands, f.name)
+		}
+	}
+	if len(shorthands) > 1 {
+		return fmt.Errorf("-%s and -%s are mutually exclusive", shorthands[0], shorthands[1])
+	}
+
+	format = strings.ToLower(strings.TrimSpace(format))
+	switch {
+	case format == "" && len(shorthands) == 1:
+		format = shorthands[0]
+	case format == "":
+		format = formatTable
+	case len(shorthands) == 1 && shorthands[0] != format:
+		return fmt.Errorf("-%s conflicts with -format %s", shorthands[0], format)
+	}
+	if !slices.Contains(formats, format) {
+		return fmt.Errorf("invalid -format %q (want %s)", format, strings.Join(formats, ", "))
+	}
+
+	opts.format = format
+	opts.jsonOut = format == formatJSON
+	opts.jsonLines = format == formatJSONL
+	opts.csv = format == formatCSV || format == formatTSV
+	opts.influx = format == formatInflux
+	return nil
+}
+
+// csvComma returns the PrintCSV delimiter for format: a tab for tsv, else
+// the default.
+func csvComma(format string) rune {
+	if format == formatTSV {
+		return '\t'
+	}
+	return 0
+}
@@ -0,0 +1,21 @@
+package main
+
+import (
+	"regexp"
+
+	"github.com/bulent/morzer/tools/tcpwatch/internal/render"
+)
+
+// grepRows returns the rows whose table line matches re (-grep). Rows are
+// matched before rendering, so every layout keeps its own structure: the
+// -sectioned section titles and the -tree process lines are drawn for the
+// rows that remain.
+func grepRows(rows []render.Row, re *regexp.Regexp, opts render.Options) []render.Row {
+	out := make([]render.Row, 0, len(rows))
+	for i, r := range rows {
+		if re.MatchString(render.RowText(rows, i, opts)) {
+			out = append(out, r)
+		}
+	}
+	return out
+}
@@ -0,0 +1,75 @@
+package main
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"time"
+
+	"github.com/bulent/morzer/tools/tcpwatch/internal/render"
+)
+
+// growthSample is one refresh's ESTABLISHED count for -growth-alert.
+type growthSample struct {
+	at time.Time
+	n  int
+}
+
+// growthAlert describes ESTABLISHED connections that grew steadily over a
+// -growth-window.
+type growthAlert struct {
+	Updated time.Time     `json:"updated"`
+	Window  time.Duration `json:"window"`
+	From    int           `json:"from"`
+	To      int           `json:"to"`
+}
+
+func (a growthAlert) String() string {
+	return fmt.Sprintf("tcpwatch: ESTABLISHED connections grew by %d over %s (%d -> %d)", a.To-a.From, a.Window, a.From, a.To)
+}
+
+// growthSink is implemented by event sinks that also deliver
+// -growth-alert alerts.
+type growthSink interface {
+	sendGrowth(a growthAlert) error
+}
+
+// checkGrowth records the ESTABLISHED count of rows and alerts on w and
+// the sinks when it never dropped over the last -growth-window and rose by
+// at least -growth-alert. The samples start over after an alert, so a
+// steady leak alerts once per window.
+func (st *watchState) checkGrowth(w io.Writer, rows []render.Row, opts options, now time.Time) {
+	n := 0
+	for _, r := range rows {
+		if r.State == "ESTABLISHED" {
+			n++
+		}
+	}
+	st.growth = append(st.growth, growthSample{at: now, n: n})
+
+	// Keep the newest sample at least a window old as the starting point.
+	start := now.Add(-opts.growthWindow)
+	for len(st.growth) > 1 && !st.growth[1].at.After(start) {
+		st.growth = st.growth[1:]
+	}
+	first := st.growth[0]
+	if first.at.After(start) || n-first.n < opts.growthAlert {
+		return
+	}
+	for i := 1; i < len(st.growth); i++ {
+		if st.growth[i].n < st.growth[i-1].n {
+			return
+		}
+	}
+
+	a := growthAlert{Updated: now, Window: now.Sub(first.at).Round(time.Second), From: first.n, To: n}
+	fmt.Fprintln(w, a)
+	for _, sink := range st.sinks {
+		if gs, ok := sink.(growthSink); ok {
+			if err := gs.sendGrowth(a); err != nil {
+				fmt.Fprintln(os.Stderr, err)
+			}
+		}
+	}
+	st.growth = []growthSample{{at: now, n: n}}
+}
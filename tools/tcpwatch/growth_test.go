@@ -0,0 +1,61 @@
+package main
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/bulent/morzer/tools/tcpwatch/internal/render"
+)
+
+func TestCheckGrowth(t *testing.T) {
+	opts, err := parseFlags([]string{"-growth-alert", "5", "-growth-window", "1m"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	established := func(n int) []render.Row {
+		rows := []render.Row{{State: "LISTEN"}, {State: "TIME_WAIT"}}
+		for range n {
+			rows = append(rows, render.Row{State: "ESTABLISHED"})
+		}
+		return rows
+	}
+	type sample struct {
+		after time.Duration // since the first sample
+		n     int
+	}
+	t0 := time.Date(2026, 1, 2, 3, 4, 5, 0, time.UTC)
+	tests := []struct {
+		name    string
+		samples []sample
+		alerts  []string
+	}{
+		{"window not full yet", []sample{{0, 10}, {30 * time.Second, 20}, {59 * time.Second, 30}}, nil},
+		{"dip", []sample{{0, 10}, {30 * time.Second, 8}, {time.Minute, 20}}, nil},
+		{"too little growth", []sample{{0, 10}, {30 * time.Second, 12}, {time.Minute, 14}}, nil},
+		// After the alert the samples start over, so 90s in is only half a
+		// window since the last one.
+		{"steady growth, then start over", []sample{{0, 10}, {30 * time.Second, 13}, {time.Minute, 16}, {90 * time.Second, 30}},
+			[]string{"tcpwatch: ESTABLISHED connections grew by 6 over 1m0s (10 -> 16)"}},
+		{"alerts once per window", []sample{{0, 10}, {time.Minute, 20}, {90 * time.Second, 30}, {2 * time.Minute, 40}},
+			[]string{
+				"tcpwatch: ESTABLISHED connections grew by 10 over 1m0s (10 -> 20)",
+				"tcpwatch: ESTABLISHED connections grew by 20 over 1m0s (20 -> 40)",
+			}},
+	}
+	for _, tt := range tests {
+		st := &watchState{}
+		var b bytes.Buffer
+		for _, s := range tt.samples {
+			st.checkGrowth(&b, established(s.n), opts, t0.Add(s.after))
+		}
+		var got []string
+		if out := strings.TrimSpace(b.String()); out != "" {
+			got = strings.Split(out, "\n")
+		}
+		if strings.Join(got, "\n") != strings.Join(tt.alerts, "\n") {
+			t.Errorf("%s: alerts\n%s\nwant\n%s", tt.name, strings.Join(got, "\n"), strings.Join(tt.alerts, "\n"))
+		}
+	}
+}
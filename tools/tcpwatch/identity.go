@@ -0,0 +1,81 @@
+package main
+
+import (
+	"fmt"
+	"net"
+	"slices"
+	"strings"
+
+	"github.com/bulent/morzer/tools/tcpwatch/internal/render"
+)
+
+// identityFields are the fields -identity accepts. local-ip and remote-ip
+// compare addresses without their port.
+var identityFields = []string{"host", "proto", "local", "local-ip", "remote", "remote-ip", "pid"}
+
+// identity lists the fields that make two rows the same connection for
+// -events, -age, -watch-only-remote-port and -watch-compare-baseline-file.
+// nil means every field (host, proto, local, remote and pid).
+type identity []string
+
+func parseIdentity(s string) (identity, error) {
+	var id identity
+	for _, f := range strings.Split(s, ",") {
+		f = strings.ToLower(strings.TrimSpace(f))
+		if f == "" {
+			continue
+		}
+		if !slices.Contains(identityFields, f) {
+			return nil, fmt.Errorf("unknown field %q (want %s)", f, strings.Join(identityFields, ", "))
+		}
+		if slices.Contains(id, f) {
+			return nil, fmt.Errorf("field %q listed twice", f)
+		}
+		id = append(id, f)
+	}
+	if len(id) == 0 {
+		return nil, fmt.Errorf("no fields given")
+	}
+	if slices.Contains(id, "local") && slices.Contains(id, "local-ip") ||
+		slices.Contains(id, "remote") && slices.Contains(id, "remote-ip") {
+		return nil, fmt.Errorf("an address and its -ip field are mutually exclusive")
+	}
+	return id, nil
+}
+
+// key returns the part of r's identity that id selects. The process start
+// time goes with the PID.
+func (id identity) key(r render.Row) connKey {
+	if id == nil {
+		return connKey{host: r.Host, proto: r.Proto, local: r.Local, remote: r.Remote, pid: r.PID, started: r.Started}
+	}
+	var k connKey
+	for _, f := range id {
+		switch f {
+		case "host":
+			k.host = r.Host
+		case "proto":
+			k.proto = r.Proto
+		case "local":
+			k.local = r.Local
+		case "local-ip":
+			k.local = addrIP(r.Local)
+		case "remote":
+			k.remote = r.Remote
+		case "remote-ip":
+			k.remote = addrIP(r.Remote)
+		case "pid":
+			k.pid, k.started = r.PID, r.Started
+		}
+	}
+	return k
+}
+
+// addrIP returns the host part of a formatted "ip:port" address.
+func addrIP(addr string) string {
+	host, _, err := net.SplitHostPort(addr)
+	if err != nil {
+		return addr
+	}
+	return host
+}
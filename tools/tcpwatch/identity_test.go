@@ -0,0 +1,78 @@
+package main
+
+import (
+	"context"
+	"slices"
+	"syscall"
+	"testing"
+	"time"
+
+	"github.com/bulent/morzer/tools/tcpwatch/internal/render"
+	gnet "github.com/shirou/gopsutil/v4/net"
+)
+
+// A PID reused by a new process must not pass for the old connection, even
+// when the addresses match.
+func TestPIDReuseIsNewConnection(t *testing.T) {
+	opts, err := parseFlags([]string{"-events"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	opts.source = fakeSource{
+		conns: []gnet.ConnectionStat{tcpConn(syscall.AF_INET, gnet.Addr{IP: "10.0.0.5", Port: 8080}, gnet.Addr{IP: "10.0.0.9", Port: 40000}, "ESTABLISHED", 42)},
+		names: procNames{42: "app"},
+	}
+
+	for _, tt := range []struct {
+		name   string
+		starts []int64 // the start time PID 42 has on each refresh
+		want   []string
+	}{
+		{"same process", []int64{1000, 1000}, nil},
+		{"PID reused", []int64{1000, 2000}, []string{eventAdded, eventRemoved}},
+	} {
+		procs := newProcResolver(time.Minute, opts.procCacheSize, opts.resolveMethod)
+		refresh := 0
+		procs.startTime = func(ctx context.Context, pid int32) (int64, error) {
+			return tt.starts[refresh], nil
+		}
+		prev, err := listTCP(context.Background(), opts, procs)
+		if err != nil {
+			t.Fatal(err)
+		}
+		refresh++
+		cur, err := listTCP(context.Background(), opts, procs)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		var got []string
+		for _, ev := range diffRows(rowsByKey(prev, opts.identity), cur, opts.identity, time.Now()) {
+			got = append(got, ev.Kind)
+		}
+		if !slices.Equal(got, tt.want) {
+			t.Errorf("%s: events %q, want %q", tt.name, got, tt.want)
+		}
+	}
+}
+
+// Rows that share a key under a coarse -identity must not look like a state
+// change on every refresh, whichever order they are listed in.
+func TestDuplicateKeysAreStable(t *testing.T) {
+	id, err := parseIdentity("local")
+	if err != nil {
+		t.Fatal(err)
+	}
+	rows := []render.Row{
+		{Proto: "tcp4", Local: "10.0.0.5:8080", Remote: "10.0.0.9:40000", State: "ESTABLISHED", PID: 42},
+		{Proto: "tcp4", Local: "10.0.0.5:8080", Remote: "10.0.0.9:40001", State: "TIME_WAIT", PID: 42},
+	}
+	reversed := slices.Clone(rows)
+	slices.Reverse(reversed)
+
+	for _, cur := range [][]render.Row{rows, reversed} {
+		if evs := diffRows(rowsByKey(rows, id), cur, id, time.Now()); len(evs) != 0 {
+			t.Errorf("unchanged rows gave events %+v", evs)
+		}
+	}
+}
@@ -0,0 +1,136 @@
+package main
+
+import (
+	"bufio"
+	"fmt"
+	"net/netip"
+	"os"
+	"strings"
+	"sync/atomic"
+
+	gnet "github.com/shirou/gopsutil/v4/net"
+)
+
+// ignoreRules are the exclusion filters of a -watch-ignore-file. The file has
+// one rule per line; blank lines and lines starting with # are skipped:
+//
+//	proc=node_exporter    # process name, case-insensitive
+//	port=9100             # local or remote port, or a range like 9100-9199
+//	remote=10.0.0.0/8     # remote IP or CIDR prefix
+//
+// A connection matching any rule is left out.
+type ignoreRules struct {
+	procs   map[string]struct{}
+	ports   portRanges
+	remotes []netip.Prefix
+}
+
+// ignoreList holds the current rules of a -watch-ignore-file. It is shared
+// by every copy of the options, so a reload (on SIGHUP) applies to the next
+// refresh.
+type ignoreList struct {
+	path  string
+	rules atomic.Pointer[ignoreRules]
+}
+
+// loadIgnoreList reads the rules in path.
+func loadIgnoreList(path string) (*ignoreList, error) {
+	l := &ignoreList{path: path}
+	if err := l.reload(); err != nil {
+		return nil, err
+	}
+	return l, nil
+}
+
+// reload rereads the file. On error the previous rules stay in effect.
+func (l *ignoreList) reload() error {
+	rules, err := readIgnoreRules(l.path)
+	if err != nil {
+		return err
+	}
+	l.rules.Store(rules)
+	return nil
+}
+
+// ignores reports whether c, owned by process procName, matches a rule.
+func (l *ignoreList) ignores(c gnet.ConnectionStat, procName string) bool {
+	rules := l.rules.Load()
+	if _, ok := rules.procs[strings.ToLower(procName)]; ok && procName != "" {
+		return true
+	}
+	if rules.ports != nil && (rules.ports.contains(c.Laddr.Port) || (c.Raddr.Port != 0 && rules.ports.contains(c.Raddr.Port))) {
+		return true
+	}
+	if len(rules.remotes) > 0 {
+		if ip, err := netip.ParseAddr(c.Raddr.IP); err == nil {
+			ip = ip.Unmap().WithZone("")
+			for _, p := range rules.remotes {
+				if p.Contains(ip) {
+					return true
+				}
+			}
+		}
+	}
+	return false
+}
+
+func readIgnoreRules(path string) (*ignoreRules, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, fmt.Errorf("-watch-ignore-file: %w", err)
+	}
+	defer f.Close()
+
+	rules := &ignoreRules{procs: make(map[string]struct{})}
+	sc := bufio.NewScanner(f)
+	for n := 1; sc.Scan(); n++ {
+		line, _, _ := strings.Cut(sc.Text(), "#")
+		line = strings.TrimSpace(line)
+		if line == "" {
+			continue
+		}
+		if err := rules.add(line); err != nil {
+			return nil, fmt.Errorf("-watch-ignore-file %s:%d: %w", path, n, err)
+		}
+	}
+	if err := sc.Err(); err != nil {
+		return nil, fmt.Errorf("-watch-ignore-file: %w", err)
+	}
+	return rules, nil
+}
+
+// add parses one "key=value" rule into r.
+func (r *ignoreRules) add(line string) error {
+	key, value, ok := strings.Cut(line, "=")
+	key, value = strings.ToLower(strings.TrimSpace(key)), strings.TrimSpace(value)
+	if !ok || value == "" {
+		return fmt.Errorf("want key=value, got %q", line)
+	}
+	switch key {
+	case "proc":
+		r.procs[strings.ToLower(value)] = struct{}{}
+	case "port":
+		rs, err := parsePortRanges(value)
+		if err != nil {
+			return err
+		}
+		r.ports = append(r.ports, rs...)
+	case "remote":
+		p, err := netip.ParsePrefix(value)
+		if err != nil {
+			ip, err := netip.ParseAddr(value)
+			if err != nil {
+				return fmt.Errorf("invalid remote %q (want an IP or CIDR prefix)", value)
+			}
+			ip = ip.Unmap()
+			p = netip.PrefixFrom(ip, ip.BitLen())
+		} else if p.Addr().Is4In6() && p.Bits() >= 96 {
+			// Connections are matched by their unmapped IP.
+			p = netip.PrefixFrom(p.Addr().Unmap(), p.Bits()-96)
+		}
+		r.remotes = append(r.remotes, p.Masked())
+	default:
+		return fmt.Errorf("unknown rule %q (want proc, port or remote)", key)
+	}
+	return nil
+}
@@ -0,0 +1,126 @@
+package main
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"syscall"
+	"testing"
+	"time"
+
+	gnet "github.com/shirou/gopsutil/v4/net"
+)
+
+// writeIgnoreFile writes content to a -watch-ignore-file in a temporary
+// directory and returns its path.
+func writeIgnoreFile(t *testing.T, content string) string {
+	t.Helper()
+	path := filepath.Join(t.TempDir(), "ignore")
+	if err := os.WriteFile(path, []byte(content), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	return path
+}
+
+func TestReadIgnoreRules(t *testing.T) {
+	tests := []struct {
+		name    string
+		content string
+		procs   []string
+		ports   portRanges
+		remotes []string
+		err     string // part of the error, if one is expected
+	}{
+		{name: "empty", content: ""},
+		{name: "comments and blank lines", content: "# routine noise\n\n  \nproc=Node_Exporter  # metrics\n", procs: []string{"node_exporter"}},
+		{name: "port and range", content: "port=9100\nport = 8000-8100, 22\n", ports: portRanges{{9100, 9100}, {8000, 8100}, {22, 22}}},
+		{name: "bare IP", content: "remote=10.0.0.9\nremote=2001:db8::1\n", remotes: []string{"10.0.0.9/32", "2001:db8::1/128"}},
+		{name: "CIDR", content: "remote=10.1.2.3/8\nremote=2001:db8::/32\n", remotes: []string{"10.0.0.0/8", "2001:db8::/32"}},
+		{name: "IPv4-mapped", content: "remote=::ffff:10.0.0.9\nremote=::ffff:10.0.0.0/104\n", remotes: []string{"10.0.0.9/32", "10.0.0.0/8"}},
+		{name: "unknown key", content: "proc=sshd\n# ok\nhost=web-01\n", err: ":3: unknown rule \"host\""},
+		{name: "no value", content: "port=\n", err: ":1: want key=value"},
+		{name: "bad port", content: "port=0\n", err: ":1: invalid port"},
+		{name: "bad remote", content: "\nremote=10.0.0\n", err: ":2: invalid remote"},
+	}
+	for _, tt := range tests {
+		rules, err := readIgnoreRules(writeIgnoreFile(t, tt.content))
+		if tt.err != "" {
+			if err == nil || !strings.Contains(err.Error(), tt.err) {
+				t.Errorf("%s: error %v, want one containing %q", tt.name, err, tt.err)
+			}
+			continue
+		}
+		if err != nil {
+			t.Errorf("%s: %v", tt.name, err)
+			continue
+		}
+		var procs []string
+		for p := range rules.procs {
+			procs = append(procs, p)
+		}
+		var remotes []string
+		for _, p := range rules.remotes {
+			remotes = append(remotes, p.String())
+		}
+		if !slices.Equal(procs, tt.procs) || !slices.Equal(rules.ports, tt.ports) || !slices.Equal(remotes, tt.remotes) {
+			t.Errorf("%s: got procs %q ports %v remotes %q, want %q %v %q", tt.name, procs, rules.ports, remotes, tt.procs, tt.ports, tt.remotes)
+		}
+	}
+}
+
+func TestIgnoreListIgnores(t *testing.T) {
+	l, err := loadIgnoreList(writeIgnoreFile(t, "proc=node_exporter\nport=9100-9199\nremote=10.0.0.0/8\n"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	conn := func(local, remote gnet.Addr) gnet.ConnectionStat {
+		return tcpConn(syscall.AF_INET, local, remote, "ESTABLISHED", 1)
+	}
+	tests := []struct {
+		name string
+		c    gnet.ConnectionStat
+		proc string
+		want bool
+	}{
+		{"process", conn(gnet.Addr{IP: "192.0.2.5", Port: 40000}, gnet.Addr{IP: "192.0.2.9", Port: 443}), "Node_Exporter", true},
+		{"other process", conn(gnet.Addr{IP: "192.0.2.5", Port: 40000}, gnet.Addr{IP: "192.0.2.9", Port: 443}), "curl", false},
+		{"no process name", conn(gnet.Addr{IP: "192.0.2.5", Port: 40000}, gnet.Addr{IP: "192.0.2.9", Port: 443}), "", false},
+		{"local port", conn(gnet.Addr{IP: "192.0.2.5", Port: 9100}, gnet.Addr{IP: "192.0.2.9", Port: 50000}), "", true},
+		{"remote port in range", conn(gnet.Addr{IP: "192.0.2.5", Port: 50000}, gnet.Addr{IP: "192.0.2.9", Port: 9150}), "", true},
+		{"remote prefix", conn(gnet.Addr{IP: "192.0.2.5", Port: 50000}, gnet.Addr{IP: "10.1.2.3", Port: 443}), "", true},
+		{"IPv4-mapped remote", conn(gnet.Addr{IP: "::ffff:192.0.2.5", Port: 50000}, gnet.Addr{IP: "::ffff:10.1.2.3", Port: 443}), "", true},
+		{"remote outside prefix", conn(gnet.Addr{IP: "192.0.2.5", Port: 50000}, gnet.Addr{IP: "11.0.0.1", Port: 443}), "", false},
+		{"listener", conn(gnet.Addr{IP: "0.0.0.0", Port: 22}, gnet.Addr{IP: "0.0.0.0"}), "", false},
+	}
+	for _, tt := range tests {
+		if got := l.ignores(tt.c, tt.proc); got != tt.want {
+			t.Errorf("%s: ignores = %v, want %v", tt.name, got, tt.want)
+		}
+	}
+}
+
+// Ignored rows are dropped before -proc is applied, so -proc can't bring
+// them back.
+func TestListTCPIgnoreFile(t *testing.T) {
+	opts, err := parseFlags([]string{"-proc", "node,curl"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	opts.source = testSource
+	if opts.ignore, err = loadIgnoreList(writeIgnoreFile(t, "proc=curl\nremote=10.0.0.9\n")); err != nil {
+		t.Fatal(err)
+	}
+	rows, err := listTCP(context.Background(), opts, newProcResolver(time.Minute, opts.procCacheSize, opts.resolveMethod))
+	if err != nil {
+		t.Fatal(err)
+	}
+	var got []string
+	for _, r := range rows {
+		got = append(got, r.Process+" "+r.Local)
+	}
+	if want := []string{"node 0.0.0.0:8080"}; !slices.Equal(got, want) {
+		t.Errorf("got %q, want %q", got, want)
+	}
+}
@@ -0,0 +1,53 @@
+package main
+
+import (
+	"bytes"
+	"io"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/bulent/morzer/tools/tcpwatch/internal/render"
+)
+
+// influxMeasurement is the measurement name of -influx lines.
+const influxMeasurement = "tcp_connections"
+
+// printInflux writes one InfluxDB line protocol point per state, protocol
+// and process each refresh, e.g.
+//
+//	tcp_connections,state=ESTABLISHED,proto=tcp4,process=curl count=2i 1700000000000000000
+//
+// which Telegraf's exec input and InfluxDB's /api/v2/write accept as is.
+// Rows without a process name get no process tag, since line protocol has
+// no empty tag values.
+func printInflux(w io.Writer, rows []render.Row, now time.Time) error {
+	var b bytes.Buffer
+	ts := strconv.FormatInt(now.UnixNano(), 10)
+	for _, g := range countGroups(rows, true) {
+		b.WriteString(influxMeasurement)
+		for _, tag := range [][2]string{{"state", g.state}, {"proto", g.proto}, {"process", g.process}} {
+			if tag[1] == "" {
+				continue
+			}
+			b.WriteByte(',')
+			b.WriteString(tag[0])
+			b.WriteByte('=')
+			b.WriteString(escapeInfluxTag(tag[1]))
+		}
+		b.WriteString(" count=")
+		b.WriteString(strconv.Itoa(g.count))
+		b.WriteString("i ")
+		b.WriteString(ts)
+		b.WriteByte('\n')
+	}
+	_, err := w.Write(b.Bytes())
+	return err
+}
+
+// escapeInfluxTag escapes a tag key or value: commas, equals signs and spaces
+// are backslash-escaped. Line protocol can't represent newlines in tags, so
+// they become spaces.
+func escapeInfluxTag(s string) string {
+	return strings.NewReplacer(`\`, `\\`, ",", `\,`, "=", `\=`, " ", `\ `, "\n", `\ `, "\r", `\ `).Replace(s)
+}
@@ -0,0 +1,22 @@
+package render
+
+import (
+	"math"
+	"strconv"
+)
+
+// HumanBytes renders n with a binary unit and one decimal, e.g. "512B",
+// "1.5K" or "3.2G". Machine formats should use the count itself.
+func HumanBytes(n uint64) string {
+	if n < 1024 {
+		return strconv.FormatUint(n, 10) + "B"
+	}
+	v := float64(n)
+	unit := 0
+	// Compare the rounded value, so 1048575 is "1.0M" and not "1024.0K".
+	for math.Round(v*10) >= 1024*10 && unit < len("KMGTPE") {
+		v /= 1024
+		unit++
+	}
+	return strconv.FormatFloat(v, 'f', 1, 64) + "KMGTPE"[unit-1:unit]
+}
@@ -0,0 +1,25 @@
+package render
+
+import "testing"
+
+func TestHumanBytes(t *testing.T) {
+	tests := []struct {
+		n    uint64
+		want string
+	}{
+		{0, "0B"},
+		{1023, "1023B"},
+		{1024, "1.0K"},
+		{1536, "1.5K"},
+		{1048575, "1.0M"},
+		{1048576, "1.0M"},
+		{3435973837, "3.2G"},
+		{1<<40 - 1, "1.0T"},
+		{1<<64 - 1, "16.0E"},
+	}
+	for _, tt := range tests {
+		if got := HumanBytes(tt.n); got != tt.want {
+			t.Errorf("HumanBytes(%d) = %q, want %q", tt.n, got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,212 @@
+package render
+
+import (
+	"fmt"
+	"slices"
+	"strings"
+)
+
+// Columns lists the table column names accepted in Options.Columns, in the
+// order they appear by default.
+var Columns = []string{"seq", "host", "proto", "family", "dir", "local", "remote", "state", "age", "in-state", "pid", "fd", "inode", "uid", "bytes", "pkts", "fds", "listener", "user", "cwd", "service", "exposure", "process"}
+
+// Presets are named column lists for common tasks.
+var Presets = map[string][]string{
+	"minimal":  {"state", "local", "remote"},
+	"security": {"proto", "remote", "process", "user", "exposure"},
+	"debug":    {"proto", "family", "dir", "local", "remote", "state", "age", "in-state", "pid", "fd", "user", "service", "exposure", "process"},
+}
+
+// DefaultColumns returns the columns PrintTable shows when Options.Columns is
+// empty: the classic layout plus whatever the Show* options add.
+func DefaultColumns(opts Options) []string {
+	var cols []string
+	if opts.ShowSeq {
+		cols = append(cols, "seq")
+	}
+	if opts.ShowHost {
+		cols = append(cols, "host")
+	}
+	cols = append(cols, "proto")
+	if opts.ShowDir {
+		cols = append(cols, "dir")
+	}
+	cols = append(cols, "local", "remote", "state")
+	if opts.ShowAge {
+		cols = append(cols, "age")
+	}
+	if opts.ShowInState {
+		cols = append(cols, "in-state")
+	}
+	cols = append(cols, "pid")
+	if opts.ShowService {
+		cols = append(cols, "service")
+	}
+	if opts.ShowSockDiag {
+		cols = append(cols, "inode", "uid")
+	}
+	if opts.ShowCounters {
+		cols = append(cols, "bytes", "pkts")
+	}
+	if opts.ShowFDs {
+		cols = append(cols, "fds")
+	}
+	if opts.ShowListener {
+		cols = append(cols, "listener")
+	}
+	if opts.ShowCwd {
+		cols = append(cols, "cwd")
+	}
+	return append(cols, "process")
+}
+
+// ParseColumns parses a comma-separated column list, rejecting unknown and
+// repeated names.
+func ParseColumns(s string) ([]string, error) {
+	var cols []string
+	for _, c := range strings.Split(s, ",") {
+		c = strings.ToLower(strings.TrimSpace(c))
+		if c == "" {
+			continue
+		}
+		if !slices.Contains(Columns, c) {
+			return nil, fmt.Errorf("unknown column %q (want %s)", c, strings.Join(Columns, ", "))
+		}
+		if slices.Contains(cols, c) {
+			return nil, fmt.Errorf("column %q listed twice", c)
+		}
+		cols = append(cols, c)
+	}
+	if len(cols) == 0 {
+		return nil, fmt.Errorf("no columns given")
+	}
+	return cols, nil
+}
+
+// RowText returns rows[i] as its table line reads, with the cells separated
+// by single spaces, so rows can be matched against a pattern before they are
+// rendered. Process names are never collapsed.
+func RowText(rows []Row, i int, opts Options) string {
+	opts.CollapseProcess = false
+	cols := opts.Columns
+	if len(cols) == 0 {
+		cols = DefaultColumns(opts)
+	}
+	cells := make([]string, len(cols))
+	for j, c := range cols {
+		cells[j] = cell(c, rows, i, opts)
+	}
+	return strings.Join(cells, " ")
+}
+
+// cell renders column name of rows[i].
+func cell(name string, rows []Row, i int, opts Options) string {
+	r := rows[i]
+	switch name {
+	case "seq":
+		if r.Seq == 0 {
+			return "-"
+		}
+		return fmt.Sprint(r.Seq)
+	case "host":
+		return dash(r.Host)
+	case "proto":
+		return r.Proto
+	case "family":
+		return dash(r.Family)
+	case "dir":
+		return dash(r.Dir)
+	case "local":
+		return r.Local
+	case "remote":
+		remote := remoteLabel(r, opts)
+		if r.Flagged {
+			return remote + " !"
+		}
+		return remote
+	case "state":
+		return stateLabel(r, opts)
+	case "age":
+		return durationLabel(r.Age, opts)
+	case "in-state":
+		return durationLabel(r.InState, opts)
+	case "pid":
+		return fmt.Sprint(r.PID)
+	case "fd":
+		if r.FD == 0 {
+			return "-"
+		}
+		return fmt.Sprint(r.FD)
+	case "inode":
+		if r.Inode == 0 {
+			return "-"
+		}
+		return fmt.Sprint(r.Inode)
+	case "uid":
+		if r.UID == nil {
+			return "-"
+		}
+		return fmt.Sprint(*r.UID)
+	case "bytes":
+		if r.Counters == nil {
+			return "-"
+		}
+		return HumanBytes(r.Counters.BytesOut) + "/" + HumanBytes(r.Counters.BytesIn)
+	case "pkts":
+		if r.Counters == nil {
+			return "-"
+		}
+		return fmt.Sprintf("%d/%d", r.Counters.PktsOut, r.Counters.PktsIn)
+	case "fds":
+		if r.Conns == 0 {
+			return "-"
+		}
+		if r.FDLimit == 0 {
+			return fmt.Sprintf("%d/-", r.Conns)
+		}
+		return fmt.Sprintf("%d/%d", r.Conns, r.FDLimit)
+	case "listener":
+		if r.ListenerPID == 0 && r.Listener == "" {
+			return "-"
+		}
+		return fmt.Sprintf("%d/%s", r.ListenerPID, dash(r.Listener))
+	case "user":
+		return dash(r.User)
+	case "cwd":
+		return dash(r.Cwd)
+	case "service":
+		return dash(r.Service)
+	case "exposure":
+		return dash(r.Exposure)
+	case "process":
+		process := strings.TrimSpace(r.Process)
+		if process == "" {
+			return "-"
+		}
+		if opts.CollapseProcess && i > 0 && r.PID > 0 && rows[i-1].PID == r.PID {
+			return `"`
+		}
+		if r.TGID > 0 {
+			return fmt.Sprintf("%s (thread of %d)", process, r.TGID)
+		}
+		return process
+	}
+	return ""
+}
+
+// remoteLabel renders the remote address, with its resolved name in place of
+// the IP when there is one.
+func remoteLabel(r Row, opts Options) string {
+	if r.RemoteHost == "" {
+		return r.Remote
+	}
+	i := strings.LastIndexByte(r.Remote, ':')
+	if i < 0 {
+		return r.Remote
+	}
+	name := r.RemoteHost + r.Remote[i:]
+	if opts.ResolveBoth {
+		return name + " (" + strings.Trim(r.Remote[:i], "[]") + ")"
+	}
+	return name
+}
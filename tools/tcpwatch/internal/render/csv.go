@@ -0,0 +1,91 @@
+package render
+
+import (
+	"encoding/csv"
+	"fmt"
+	"io"
+	"strings"
+	"time"
+)
+
+// csvColumn describes one CSV column; Type is used for the -csv-comment
+// type hint line.
+type csvColumn struct {
+	Name  string
+	Type  string
+	Value func(Row) string
+}
+
+func csvColumns(opts Options) []csvColumn {
+	cols := []csvColumn{
+		{"updated", "timestamp (RFC 3339)", func(Row) string { return opts.Now.Format(time.RFC3339) }},
+	}
+	if opts.ShowSeq {
+		cols = append(cols, csvColumn{"seq", "integer", func(r Row) string { return fmt.Sprint(r.Seq) }})
+	}
+	if opts.ShowHost {
+		cols = append(cols, csvColumn{"host", "string", func(r Row) string { return r.Host }})
+	}
+	cols = append(cols, csvColumn{"proto", "string", func(r Row) string { return r.Proto }})
+	if opts.ShowDir {
+		cols = append(cols, csvColumn{"dir", "string", func(r Row) string { return r.Dir }})
+	}
+	cols = append(cols,
+		csvColumn{"local", "string (host:port)", func(r Row) string { return r.Local }},
+		csvColumn{"remote", "string (host:port)", func(r Row) string { return r.Remote }},
+		csvColumn{"state", "string", func(r Row) string { return r.State }},
+	)
+	if opts.ShowAge {
+		cols = append(cols, csvColumn{"age_ns", "integer (nanoseconds)", func(r Row) string { return fmt.Sprint(int64(r.Age)) }})
+	}
+	cols = append(cols,
+		csvColumn{"pid", "integer", func(r Row) string { return fmt.Sprint(r.PID) }},
+	)
+	if opts.ShowService {
+		cols = append(cols, csvColumn{"service", "string", func(r Row) string { return r.Service }})
+	}
+	cols = append(cols, csvColumn{"process", "string", func(r Row) string { return r.Process }})
+	return cols
+}
+
+// PrintCSV writes rows as CSV, sorted like PrintTable. ShowHeader controls
+// the header row; TypeComment prepends a "#" line documenting column types.
+func PrintCSV(w io.Writer, rows []Row, opts Options) error {
+	SortRows(rows, opts.SortKey)
+	cols := csvColumns(opts)
+
+	if opts.TypeComment {
+		parts := make([]string, len(cols))
+		for i, c := range cols {
+			parts[i] = c.Name + ": " + c.Type
+		}
+		if _, err := fmt.Fprintf(w, "# %s\n", strings.Join(parts, ", ")); err != nil {
+			return err
+		}
+	}
+
+	cw := csv.NewWriter(w)
+	if opts.CSVComma != 0 {
+		cw.Comma = opts.CSVComma
+	}
+	if opts.ShowHeader {
+		header := make([]string, len(cols))
+		for i, c := range cols {
+			header[i] = c.Name
+		}
+		if err := cw.Write(header); err != nil {
+			return err
+		}
+	}
+	record := make([]string, len(cols))
+	for _, r := range rows {
+		for i, c := range cols {
+			record[i] = c.Value(r)
+		}
+		if err := cw.Write(record); err != nil {
+			return err
+		}
+	}
+	cw.Flush()
+	return cw.Error()
+}
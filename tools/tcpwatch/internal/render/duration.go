@@ -0,0 +1,41 @@
+package render
+
+import (
+	"strconv"
+	"time"
+)
+
+var durationUnits = []struct {
+	size time.Duration
+	name string
+}{
+	{24 * time.Hour, "d"},
+	{time.Hour, "h"},
+	{time.Minute, "m"},
+	{time.Second, "s"},
+}
+
+// HumanDuration renders d compactly with at most two units, e.g. "350ms",
+// "42s", "1h2m" or "2d". Machine formats should use the Duration itself.
+func HumanDuration(d time.Duration) string {
+	if d < 0 {
+		d = -d
+	}
+	if d < time.Second {
+		return strconv.FormatInt(d.Milliseconds(), 10) + "ms"
+	}
+	for i, u := range durationUnits {
+		if d < u.size {
+			continue
+		}
+		out := strconv.FormatInt(int64(d/u.size), 10) + u.name
+		if i+1 < len(durationUnits) {
+			next := durationUnits[i+1]
+			if n := d % u.size / next.size; n > 0 {
+				out += strconv.FormatInt(int64(n), 10) + next.name
+			}
+		}
+		return out
+	}
+	return "0s"
+}
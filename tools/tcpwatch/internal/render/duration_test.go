@@ -0,0 +1,29 @@
+package render
+
+import (
+	"testing"
+	"time"
+)
+
+func TestHumanDuration(t *testing.T) {
+	tests := []struct {
+		d    time.Duration
+		want string
+	}{
+		{0, "0ms"},
+		{999 * time.Millisecond, "999ms"},
+		{time.Second, "1s"},
+		{1500 * time.Millisecond, "1s"},
+		{59*time.Minute + 59*time.Second, "59m59s"},
+		{time.Hour, "1h"},
+		{time.Hour + 2*time.Minute + 3*time.Second, "1h2m"},
+		{48 * time.Hour, "2d"},
+		{25 * time.Hour, "1d1h"},
+		{-42 * time.Second, "42s"},
+	}
+	for _, tt := range tests {
+		if got := HumanDuration(tt.d); got != tt.want {
+			t.Errorf("HumanDuration(%s) = %q, want %q", tt.d, got, tt.want)
+		}
+	}
+}
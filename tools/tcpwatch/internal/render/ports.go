@@ -0,0 +1,167 @@
+package render
+
+import (
+	"fmt"
+	"io"
+	"net"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Port is a listening socket as shown in the open ports report.
+type Port struct {
+	Port  int
+	Proto string
+	// Address is "*" when the socket is bound to all interfaces.
+	Address string
+	PID     int32
+	Process string
+}
+
+// Ports converts LISTEN rows into ports sorted by port number.
+func Ports(rows []Row) []Port {
+	out := make([]Port, 0, len(rows))
+	for _, r := range rows {
+		host, portStr, err := net.SplitHostPort(r.Local)
+		if err != nil {
+			continue
+		}
+		port, err := strconv.Atoi(portStr)
+		if err != nil {
+			continue
+		}
+		out = append(out, Port{
+			Port:    port,
+			Proto:   r.Proto,
+			Address: bindAddress(host),
+			PID:     r.PID,
+			Process: r.Process,
+		})
+	}
+
+	sort.SliceStable(out, func(i, j int) bool {
+		if out[i].Port != out[j].Port {
+			return out[i].Port < out[j].Port
+		}
+		if out[i].Proto != out[j].Proto {
+			return out[i].Proto < out[j].Proto
+		}
+		if out[i].Address != out[j].Address {
+			return out[i].Address < out[j].Address
+		}
+		if out[i].PID != out[j].PID {
+			return out[i].PID < out[j].PID
+		}
+		return out[i].Process < out[j].Process
+	})
+	return out
+}
+
+func bindAddress(host string) string {
+	if host == "" || host == "*" {
+		return "*"
+	}
+	if ip := net.ParseIP(host); ip != nil && ip.IsUnspecified() {
+		return "*"
+	}
+	return host
+}
+
+func PrintPorts(w io.Writer, ports []Port, opts Options) {
+	tw := newTabWriter(w, opts.Layout)
+	if opts.Title != "" {
+		fmt.Fprintln(tw, opts.Title)
+	}
+	if !opts.Now.IsZero() {
+		fmt.Fprintf(tw, "Updated:\t%s\n", opts.Now.Format(time.RFC3339))
+	}
+	if opts.ShowHeader {
+		fmt.Fprintln(tw, "PORT\tPROTO\tADDRESS\tPROCESS\tPID")
+	}
+
+	for _, p := range ports {
+		process := strings.TrimSpace(p.Process)
+		if process == "" {
+			process = "-"
+		}
+		fmt.Fprintf(tw, "%d\t%s\t%s\t%s\t%d\n", p.Port, p.Proto, p.Address, process, p.PID)
+	}
+	_ = tw.Flush()
+}
+
+// SharedPort groups the listening sockets bound to the same address and port
+// by different processes, e.g. SO_REUSEPORT workers or children that
+// inherited the socket.
+type SharedPort struct {
+	Port    int
+	Proto   string
+	Address string
+	// Sharers lists one entry per PID, ordered by PID.
+	Sharers []Sharer
+}
+
+// Sharer is a process listening on a SharedPort.
+type Sharer struct {
+	PID     int32
+	Process string
+}
+
+// SharedPorts groups ports (as returned by Ports) by proto, address and port.
+// Every listening socket is included; those with a single sharer aren't
+// shared.
+func SharedPorts(ports []Port) []SharedPort {
+	var out []SharedPort
+	for _, p := range ports {
+		n := len(out)
+		if n == 0 || out[n-1].Port != p.Port || out[n-1].Proto != p.Proto || out[n-1].Address != p.Address {
+			out = append(out, SharedPort{Port: p.Port, Proto: p.Proto, Address: p.Address})
+			n++
+		}
+		// Ports sorts by PID within a group, so a repeated PID is adjacent.
+		s := &out[n-1]
+		if k := len(s.Sharers); k > 0 && s.Sharers[k-1].PID == p.PID {
+			continue
+		}
+		s.Sharers = append(s.Sharers, Sharer{PID: p.PID, Process: p.Process})
+	}
+	return out
+}
+
+// PrintSharedPorts prints one line per listening address with the number of
+// processes sharing it and their PIDs, grouped by process name, e.g.
+// "nginx[101,102,103]".
+func PrintSharedPorts(w io.Writer, ports []SharedPort, opts Options) {
+	tw := newTabWriter(w, opts.Layout)
+	if opts.Title != "" {
+		fmt.Fprintln(tw, opts.Title)
+	}
+	if !opts.Now.IsZero() {
+		fmt.Fprintf(tw, "Updated:\t%s\n", opts.Now.Format(time.RFC3339))
+	}
+	if opts.ShowHeader {
+		fmt.Fprintln(tw, "PORT\tPROTO\tADDRESS\tSHARERS\tPROCESSES")
+	}
+
+	for _, p := range ports {
+		var names []string
+		pids := make(map[string][]string)
+		for _, s := range p.Sharers {
+			name := strings.TrimSpace(s.Process)
+			if name == "" {
+				name = "-"
+			}
+			if _, ok := pids[name]; !ok {
+				names = append(names, name)
+			}
+			pids[name] = append(pids[name], strconv.Itoa(int(s.PID)))
+		}
+		groups := make([]string, len(names))
+		for i, name := range names {
+			groups[i] = name + "[" + strings.Join(pids[name], ",") + "]"
+		}
+		fmt.Fprintf(tw, "%d\t%s\t%s\t%d\t%s\n", p.Port, p.Proto, p.Address, len(p.Sharers), strings.Join(groups, " "))
+	}
+	_ = tw.Flush()
+}
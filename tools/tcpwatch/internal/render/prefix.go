@@ -0,0 +1,37 @@
+package render
+
+import (
+	"bytes"
+	"io"
+)
+
+// prefixWriter writes prefix at the start of every line written through it.
+type prefixWriter struct {
+	w      io.Writer
+	prefix []byte
+	// midLine is set when the last write didn't end with a newline.
+	midLine bool
+}
+
+func (p *prefixWriter) Write(b []byte) (int, error) {
+	n := len(b)
+	var out bytes.Buffer
+	for len(b) > 0 {
+		if !p.midLine {
+			out.Write(p.prefix)
+		}
+		i := bytes.IndexByte(b, '\n')
+		if i < 0 {
+			out.Write(b)
+			p.midLine = true
+			break
+		}
+		out.Write(b[:i+1])
+		p.midLine = false
+		b = b[i+1:]
+	}
+	if _, err := p.w.Write(out.Bytes()); err != nil {
+		return 0, err
+	}
+	return n, nil
+}
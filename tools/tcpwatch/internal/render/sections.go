@@ -0,0 +1,52 @@
+package render
+
+import (
+	"fmt"
+	"io"
+	"time"
+)
+
+// PrintSectioned prints rows as two tables, INBOUND (connections to this
+// host and its listeners) and OUTBOUND, split on Row.Dir. Rows without a
+// direction (e.g. unix sockets) go in a third OTHER table, printed only when
+// there are any. Each section is sorted and aligned on its own; the title and
+// update time are printed once above them all.
+func PrintSectioned(w io.Writer, rows []Row, opts Options) {
+	var in, out, other []Row
+	for _, r := range rows {
+		switch r.Dir {
+		case "in", "listen":
+			in = append(in, r)
+		case "out":
+			out = append(out, r)
+		default:
+			other = append(other, r)
+		}
+	}
+
+	if opts.LinePrefix != "" {
+		w = &prefixWriter{w: w, prefix: []byte(opts.LinePrefix)}
+	}
+	if opts.Title != "" {
+		fmt.Fprintln(w, opts.Title)
+	}
+	if !opts.Now.IsZero() {
+		fmt.Fprintf(w, "Updated:  %s\n", opts.Now.Format(time.RFC3339))
+	}
+	section := opts
+	section.Title, section.Now, section.LinePrefix = "", time.Time{}, ""
+	if opts.Width > 0 {
+		// w already writes the prefix, so the sections get what is left.
+		section.Width = widthAfterPrefix(opts.Width, opts.LinePrefix)
+	}
+	for _, s := range []struct {
+		label string
+		rows  []Row
+	}{{"INBOUND", in}, {"OUTBOUND", out}, {"OTHER", other}} {
+		if s.label == "OTHER" && len(s.rows) == 0 {
+			continue
+		}
+		fmt.Fprintf(w, "\n%s (%d)\n", s.label, len(s.rows))
+		PrintTable(w, s.rows, section)
+	}
+}
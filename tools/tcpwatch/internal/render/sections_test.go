@@ -0,0 +1,29 @@
+package render
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+)
+
+func TestPrintSectionedOther(t *testing.T) {
+	rows := []Row{
+		{Proto: "tcp4", Local: "0.0.0.0:443", Remote: "0.0.0.0:0", State: "LISTEN", Dir: "listen"},
+		{Proto: "tcp4", Local: "10.0.0.5:443", Remote: "192.0.2.1:50000", State: "ESTABLISHED", Dir: "in"},
+		{Proto: "tcp4", Local: "10.0.0.5:40000", Remote: "192.0.2.9:80", State: "ESTABLISHED", Dir: "out"},
+		{Proto: "unix", Local: "/run/app.sock", State: "ESTABLISHED"},
+	}
+	var b bytes.Buffer
+	PrintSectioned(&b, rows, Options{})
+	for _, want := range []string{"\nINBOUND (2)\n", "\nOUTBOUND (1)\n", "\nOTHER (1)\n"} {
+		if !strings.Contains(b.String(), want) {
+			t.Errorf("output has no %q:\n%s", want, b.String())
+		}
+	}
+
+	b.Reset()
+	PrintSectioned(&b, rows[:3], Options{})
+	if strings.Contains(b.String(), "OTHER") {
+		t.Errorf("empty OTHER section printed:\n%s", b.String())
+	}
+}
@@ -3,6 +3,7 @@ package render
 import (
 	"fmt"
 	"io"
+	"slices"
 	"sort"
 	"strings"
 	"text/tabwriter"
@@ -17,45 +18,386 @@ type Row struct {
 	PID    int32
 	// Process may be empty if unavailable.
 	Process string
+	// TGID is the owning process's PID when PID is a Linux thread ID
+	// (-proc-include-threads); Process is then that process's name.
+	TGID int32 `json:",omitempty"`
+	// PPID is the owning process's parent (-pid-tree).
+	PPID int32 `json:",omitempty"`
+	// Dir is "in", "out" or "listen" when direction classification is on.
+	Dir string `json:",omitempty"`
+	// Service is the well-known service name of the serving side's port.
+	Service string `json:",omitempty"`
+	// StateCode is the OS's numeric TCP state, when known and requested.
+	StateCode *int `json:",omitempty"`
+	// Listening marks a listening socket. It is always present in JSON so
+	// consumers needn't derive it from State, which some platforms leave
+	// empty for listeners.
+	Listening bool
+	// Age is how long the connection has been observed, in nanoseconds in
+	// JSON.
+	Age time.Duration `json:",omitempty"`
+	// Seq numbers connections in the order they were first seen (-seq).
+	Seq int `json:",omitempty"`
+	// InState is how long the connection has been in its current state
+	// (-stuck), in nanoseconds in JSON.
+	InState time.Duration `json:",omitempty"`
+	// Family, FD, User and Exposure are only filled when their table
+	// column is selected. Exposure says who can reach a LISTEN socket:
+	// "loopback", "any" (wildcard bind) or "iface" (one address).
+	Family   string `json:",omitempty"`
+	FD       uint32 `json:",omitempty"`
+	User     string `json:",omitempty"`
+	Exposure string `json:",omitempty"`
+	// RemoteHost is the remote IP's reverse DNS name (-resolve).
+	RemoteHost string `json:",omitempty"`
+	// Flagged marks a row whose remote host has unusually many
+	// connections (-flag-remote-over).
+	Flagged bool `json:",omitempty"`
+	// Conns is the process's connection count and FDLimit its soft limit
+	// on open files (-per-process-limit). Warn marks a row that needs
+	// attention (near the fd limit, or on a -warn-port port); it is shown
+	// in red when color is on.
+	Conns   int    `json:",omitempty"`
+	FDLimit uint64 `json:",omitempty"`
+	Warn    bool   `json:",omitempty"`
+	// ListenerPID and Listener identify the listening socket's owner that
+	// accepted an inbound connection (-show-listener).
+	ListenerPID int32  `json:",omitempty"`
+	Listener    string `json:",omitempty"`
+	// Cwd is the owning process's working directory (-show-cwd).
+	Cwd string `json:",omitempty"`
+	// Inode and UID come from Linux sock_diag (-sock-diag).
+	Inode uint32  `json:",omitempty"`
+	UID   *uint32 `json:",omitempty"`
+	// Counters are the socket's cumulative traffic totals (-counters).
+	Counters *Counters `json:",omitempty"`
+	// Started is the owning process's start time (Unix ms), used to tell
+	// connections of a reused PID apart. It isn't part of any output.
+	Started int64 `json:"-"`
+	// Host names the machine the row came from (-host-label, -aggregate).
+	Host string `json:",omitempty"`
+	// Invalid says why the row failed -strict validation.
+	Invalid string `json:",omitempty"`
+}
+
+// Counters are a socket's traffic totals since it was opened. BytesOut
+// counts bytes the peer acknowledged; packets are TCP segments.
+type Counters struct {
+	BytesOut, BytesIn uint64
+	PktsOut, PktsIn   uint64
 }
 
 type Options struct {
 	ShowHeader bool
 	Now        time.Time
 	Title      string
+	// CollapseProcess shows the process name only on the first row of a
+	// run of rows with the same PID and a ditto mark on the rest.
+	CollapseProcess bool
+	// Highlight renders rows containing this substring (in any field,
+	// case-insensitive) in bold and dims the rest. Requires Color.
+	Highlight string
+	Color     bool
+	// ShowDir adds a DIR column after PROTO.
+	ShowDir bool
+	// ShowService adds a SERVICE column before PROCESS.
+	ShowService bool
+	// Layout controls column spacing. The zero value uses DefaultLayout.
+	Layout Layout
+	// TypeComment makes PrintCSV prepend a comment line with column types.
+	TypeComment bool
+	// CSVComma is the field delimiter PrintCSV uses; zero means a comma.
+	CSVComma rune
+	// StateCodes appends the numeric state to STATE, e.g. ESTABLISHED(1).
+	StateCodes bool
+	// ShowHost adds a leading HOST column.
+	ShowHost bool
+	// ShowFDs adds an FDS column (connections/fd limit) before PROCESS.
+	ShowFDs bool
+	// ResolveBoth shows resolved remote names with the IP, as
+	// "name:port (ip)", instead of only the name.
+	ResolveBoth bool
+	// ShowListener adds a LISTENER column before PROCESS.
+	ShowListener bool
+	// ShowCwd adds a CWD column before PROCESS.
+	ShowCwd bool
+	// ShowCounters adds BYTES and PKTS columns (out/in) before PROCESS.
+	ShowCounters bool
+	// ShowSockDiag adds INODE and UID columns before PROCESS.
+	ShowSockDiag bool
+	// ShowSeq adds a leading SEQ column.
+	ShowSeq bool
+	// ShowAge adds an AGE column after STATE.
+	ShowAge bool
+	// ShowInState adds an IN-STATE column after STATE (and AGE).
+	ShowInState bool
+	// HumanDurations renders duration columns compactly (see HumanDuration)
+	// instead of as Go duration strings.
+	HumanDurations bool
+	// Width, if positive, truncates cells so the table fits in this many
+	// characters, LinePrefix included.
+	Width int
+	// MaxRemoteWidth, if positive, shortens longer REMOTE cells by cutting
+	// out their middle.
+	MaxRemoteWidth int
+	// Columns selects and orders the table columns (see Columns). Empty
+	// means DefaultColumns.
+	Columns []string
+	// SortKey selects the primary sort column; see SortKeys.
+	SortKey string
+	// LinePrefix is written at the start of every output line.
+	LinePrefix string
+	// Separator, if set, prints the table unaligned with cells joined by it
+	// instead of padded into columns. Cells containing it are quoted.
+	Separator string
+	// EmptyMessage is printed below the header when there are no rows, so
+	// an empty table doesn't look like a failure.
+	EmptyMessage string
+}
+
+func stateLabel(r Row, opts Options) string {
+	if opts.StateCodes && r.StateCode != nil {
+		return fmt.Sprintf("%s(%d)", r.State, *r.StateCode)
+	}
+	return r.State
+}
+
+func durationLabel(d time.Duration, opts Options) string {
+	if opts.HumanDurations {
+		return HumanDuration(d)
+	}
+	return d.Round(time.Millisecond).String()
+}
+
+// Layout holds the text/tabwriter parameters used for tables.
+type Layout struct {
+	MinWidth int
+	TabWidth int
+	Padding  int
+	PadChar  byte
+}
+
+// DefaultLayout is the spacing tcpwatch has always used.
+var DefaultLayout = Layout{MinWidth: 0, TabWidth: 4, Padding: 2, PadChar: ' '}
+
+// padding returns the space tabwriter leaves between columns.
+func (l Layout) padding() int {
+	if l == (Layout{}) {
+		return DefaultLayout.Padding
+	}
+	return l.Padding
+}
+
+func newTabWriter(w io.Writer, l Layout) *tabwriter.Writer {
+	if l == (Layout{}) {
+		l = DefaultLayout
+	}
+	if l.PadChar == 0 {
+		l.PadChar = ' '
+	}
+	return tabwriter.NewWriter(w, l.MinWidth, l.TabWidth, l.Padding, l.PadChar, 0)
+}
+
+const (
+	ansiBold  = "\033[1m"
+	ansiDim   = "\033[2m"
+	ansiReset = "\033[0m"
+	// ansiRed and ansiDefault (the default foreground) have the same length
+	// so rows stay aligned whichever they start with.
+	ansiRed     = "\033[31m"
+	ansiDefault = "\033[39m"
+)
+
+// SortKeys are the accepted values for Options.SortKey.
+var SortKeys = []string{"state", "local", "remote", "pid", "process", "proto", "age"}
+
+// SortRows orders rows by key (one of SortKeys; "" means "state"), then by
+// host, state, local, remote and PID. The remaining fields break ties so the order
+// is total and repeated refreshes of the same data render identically (no
+// flicker in watch mode, meaningful diffs between JSON snapshots).
+func SortRows(rows []Row, key string) {
+	sort.SliceStable(rows, func(i, j int) bool {
+		a, b := rows[i], rows[j]
+		switch key {
+		case "local":
+			if a.Local != b.Local {
+				return a.Local < b.Local
+			}
+		case "remote":
+			if a.Remote != b.Remote {
+				return a.Remote < b.Remote
+			}
+		case "pid":
+			if a.PID != b.PID {
+				return a.PID < b.PID
+			}
+		case "process":
+			if a.Process != b.Process {
+				return a.Process < b.Process
+			}
+		case "proto":
+			if a.Proto != b.Proto {
+				return a.Proto < b.Proto
+			}
+		case "age":
+			// Oldest first; rows not aged yet count as zero.
+			if a.Age != b.Age {
+				return a.Age > b.Age
+			}
+		}
+		return defaultLess(a, b)
+	})
+}
+
+func defaultLess(a, b Row) bool {
+	// Host is only set in -aggregate output, where rows group by host.
+	if a.Host != b.Host {
+		return a.Host < b.Host
+	}
+	if a.State != b.State {
+		return a.State < b.State
+	}
+	if a.Local != b.Local {
+		return a.Local < b.Local
+	}
+	if a.Remote != b.Remote {
+		return a.Remote < b.Remote
+	}
+	if a.PID != b.PID {
+		return a.PID < b.PID
+	}
+	if a.Proto != b.Proto {
+		return a.Proto < b.Proto
+	}
+	return a.Process < b.Process
 }
 
 func PrintTable(w io.Writer, rows []Row, opts Options) {
-	sort.Slice(rows, func(i, j int) bool {
-		if rows[i].State != rows[j].State {
-			return rows[i].State < rows[j].State
+	SortRows(rows, opts.SortKey)
+	if opts.LinePrefix != "" {
+		w = &prefixWriter{w: w, prefix: []byte(opts.LinePrefix)}
+	}
+
+	// Every line starts with escape sequences of the same total length when
+	// coloring so tabwriter's column widths stay aligned.
+	highlight := opts.Color && opts.Highlight != ""
+	warn := opts.Color && slices.ContainsFunc(rows, func(r Row) bool { return r.Warn })
+	lead := ""
+	if highlight {
+		lead += ansiReset
+	}
+	if warn {
+		lead += ansiDefault
+	}
+
+	cols := opts.Columns
+	if len(cols) == 0 {
+		cols = DefaultColumns(opts)
+	}
+	var table [][]string
+	if opts.ShowHeader {
+		header := make([]string, len(cols))
+		for i, c := range cols {
+			header[i] = strings.ToUpper(c)
 		}
-		if rows[i].Local != rows[j].Local {
-			return rows[i].Local < rows[j].Local
+		table = append(table, header)
+	}
+	for i := range rows {
+		cells := make([]string, len(cols))
+		for j, c := range cols {
+			cells[j] = cell(c, rows, i, opts)
+			if c == "remote" && opts.MaxRemoteWidth > 0 {
+				cells[j] = truncateMiddle(cells[j], opts.MaxRemoteWidth)
+			}
 		}
-		if rows[i].Remote != rows[j].Remote {
-			return rows[i].Remote < rows[j].Remote
+		table = append(table, cells)
+	}
+	if opts.Separator != "" {
+		printSeparated(w, table, opts)
+		if len(rows) == 0 && opts.EmptyMessage != "" {
+			fmt.Fprintln(w, opts.EmptyMessage)
 		}
-		return rows[i].PID < rows[j].PID
-	})
+		return
+	}
 
-	tw := tabwriter.NewWriter(w, 0, 4, 2, ' ', 0)
+	tw := newTabWriter(w, opts.Layout)
 	if opts.Title != "" {
 		fmt.Fprintln(tw, opts.Title)
 	}
 	if !opts.Now.IsZero() {
-		fmt.Fprintf(tw, "Updated:\t%s\n", opts.Now.Format(time.RFC3339))
+		fmt.Fprintf(tw, "%sUpdated:\t%s\n", lead, opts.Now.Format(time.RFC3339))
 	}
-	if opts.ShowHeader {
-		fmt.Fprintln(tw, "PROTO\tLOCAL\tREMOTE\tSTATE\tPID\tPROCESS")
+	if opts.Width > 0 {
+		first := 0
+		if !opts.Now.IsZero() {
+			first = len("Updated:")
+		}
+		fitWidth(table, widthAfterPrefix(opts.Width, opts.LinePrefix), opts.Layout.padding(), first)
 	}
 
-	for _, r := range rows {
-		process := strings.TrimSpace(r.Process)
-		if process == "" {
-			process = "-"
+	for i, cells := range table {
+		start, end := lead, ""
+		if opts.ShowHeader {
+			i--
 		}
-		fmt.Fprintf(tw, "%s\t%s\t%s\t%s\t%d\t%s\n", r.Proto, r.Local, r.Remote, r.State, r.PID, process)
+		if i >= 0 && (highlight || warn) {
+			start, end = "", ansiReset
+			if highlight {
+				if rowMatches(rows[i], opts.Highlight) {
+					start += ansiBold
+				} else {
+					start += ansiDim
+				}
+			}
+			if warn {
+				if rows[i].Warn {
+					start += ansiRed
+				} else {
+					start += ansiDefault
+				}
+			}
+		}
+		fmt.Fprintf(tw, "%s%s%s\n", start, strings.Join(cells, "\t"), end)
 	}
 	_ = tw.Flush()
+	if len(rows) == 0 && opts.EmptyMessage != "" {
+		fmt.Fprintln(w, opts.EmptyMessage)
+	}
+}
+
+// printSeparated writes the title, update time and table lines with cells
+// joined by opts.Separator, without alignment or color.
+func printSeparated(w io.Writer, table [][]string, opts Options) {
+	sep := opts.Separator
+	if opts.Title != "" {
+		fmt.Fprintln(w, opts.Title)
+	}
+	if !opts.Now.IsZero() {
+		fmt.Fprintf(w, "Updated:%s%s\n", sep, opts.Now.Format(time.RFC3339))
+	}
+	for _, cells := range table {
+		for i, c := range cells {
+			if strings.Contains(c, sep) || strings.HasPrefix(c, `"`) {
+				cells[i] = `"` + strings.ReplaceAll(c, `"`, `""`) + `"`
+			}
+		}
+		fmt.Fprintln(w, strings.Join(cells, sep))
+	}
+}
+
+func dash(s string) string {
+	if s == "" {
+		return "-"
+	}
+	return s
+}
+
+func rowMatches(r Row, query string) bool {
+	q := strings.ToLower(query)
+	for _, f := range []string{r.Proto, r.Dir, r.Local, r.Remote, r.RemoteHost, r.State, fmt.Sprint(r.PID), r.Service, r.Process} {
+		if strings.Contains(strings.ToLower(f), q) {
+			return true
+		}
+	}
+	return false
 }
@@ -0,0 +1,119 @@
+package render
+
+import (
+	"bytes"
+	"math/rand/v2"
+	"slices"
+	"testing"
+	"time"
+)
+
+// sortFixture has several rows that tie on every sort key, so only the
+// tie-breakers decide their order.
+var sortFixture = []Row{
+	{Proto: "tcp4", Local: "10.0.0.5:443", Remote: "1.2.3.4:5000", State: "ESTABLISHED", PID: 100, Process: "nginx"},
+	{Proto: "tcp4", Local: "10.0.0.5:443", Remote: "1.2.3.5:5000", State: "ESTABLISHED", PID: 100, Process: "nginx"},
+	{Proto: "tcp4", Local: "10.0.0.5:443", Remote: "1.2.3.4:5000", State: "ESTABLISHED", PID: 101, Process: "nginx"},
+	{Proto: "tcp6", Local: "10.0.0.5:443", Remote: "1.2.3.4:5000", State: "ESTABLISHED", PID: 100, Process: "nginx"},
+	{Proto: "tcp4", Local: "0.0.0.0:443", Remote: "0.0.0.0:0", State: "LISTEN", PID: 100, Process: "nginx"},
+	{Proto: "tcp4", Local: "0.0.0.0:22", Remote: "0.0.0.0:0", State: "LISTEN", PID: 7, Process: "sshd"},
+	{Proto: "tcp4", Local: "10.0.0.5:22", Remote: "1.2.3.4:6000", State: "ESTABLISHED", PID: 7, Process: "sshd"},
+	{Host: "b", Proto: "tcp4", Local: "10.0.0.5:443", Remote: "1.2.3.4:5000", State: "ESTABLISHED", PID: 100, Process: "nginx"},
+}
+
+// Rows with equal sort keys must come out in the same order whatever order
+// they arrived in, so refreshes don't reshuffle and JSON snapshots diff
+// cleanly.
+func TestSortRowsDeterministic(t *testing.T) {
+	rng := rand.New(rand.NewPCG(1, 2))
+	for _, key := range SortKeys {
+		want := slices.Clone(sortFixture)
+		SortRows(want, key)
+		for range 50 {
+			rows := slices.Clone(sortFixture)
+			rng.Shuffle(len(rows), func(i, j int) { rows[i], rows[j] = rows[j], rows[i] })
+			SortRows(rows, key)
+			if !slices.Equal(rows, want) {
+				t.Fatalf("-sort %s: order depends on the input order:\n got %v\nwant %v", key, rows, want)
+			}
+		}
+	}
+}
+
+func TestSortRowsTieBreak(t *testing.T) {
+	rows := slices.Clone(sortFixture)
+	SortRows(rows, "process")
+	var got []string
+	for _, r := range rows {
+		got = append(got, r.Host+" "+r.Process+" "+r.State+" "+r.Local+" "+r.Remote+" "+r.Proto)
+	}
+	want := []string{
+		" nginx ESTABLISHED 10.0.0.5:443 1.2.3.4:5000 tcp4", // PID 100
+		" nginx ESTABLISHED 10.0.0.5:443 1.2.3.4:5000 tcp6",
+		" nginx ESTABLISHED 10.0.0.5:443 1.2.3.4:5000 tcp4", // PID 101
+		" nginx ESTABLISHED 10.0.0.5:443 1.2.3.5:5000 tcp4",
+		" nginx LISTEN 0.0.0.0:443 0.0.0.0:0 tcp4",
+		"b nginx ESTABLISHED 10.0.0.5:443 1.2.3.4:5000 tcp4",
+		" sshd ESTABLISHED 10.0.0.5:22 1.2.3.4:6000 tcp4",
+		" sshd LISTEN 0.0.0.0:22 0.0.0.0:0 tcp4",
+	}
+	if !slices.Equal(got, want) {
+		t.Errorf("got\n%q\nwant\n%q", got, want)
+	}
+}
+
+func TestPrintTableLayoutPadding(t *testing.T) {
+	rows := []Row{
+		{Proto: "tcp4", Local: "10.0.0.5:51000", Remote: "93.184.216.34:443", State: "ESTABLISHED", PID: 100, Process: "curl"},
+		{Proto: "tcp6", Local: "[::]:22", Remote: "[::]:0", State: "LISTEN", PID: 7, Process: "sshd"},
+	}
+	opts := Options{ShowHeader: true, Now: time.Date(2026, 1, 2, 3, 4, 5, 0, time.UTC), Title: "T"}
+	tests := []struct {
+		layout Layout
+		want   string
+	}{
+		// The widest cell of each column is followed by exactly Padding
+		// characters.
+		{Layout{TabWidth: 4, Padding: 5, PadChar: ' '}, `T
+Updated:     2026-01-02T03:04:05Z
+PROTO        LOCAL              REMOTE                STATE           PID     PROCESS
+tcp4         10.0.0.5:51000     93.184.216.34:443     ESTABLISHED     100     curl
+tcp6         [::]:22            [::]:0                LISTEN          7       sshd
+`},
+		{Layout{TabWidth: 4, Padding: 1, PadChar: '.'}, `T
+Updated:.2026-01-02T03:04:05Z
+PROTO....LOCAL..........REMOTE............STATE.......PID.PROCESS
+tcp4.....10.0.0.5:51000.93.184.216.34:443.ESTABLISHED.100.curl
+tcp6.....[::]:22........[::]:0............LISTEN......7...sshd
+`},
+	}
+	for _, tt := range tests {
+		opts.Layout = tt.layout
+		var b bytes.Buffer
+		PrintTable(&b, slices.Clone(rows), opts)
+		if b.String() != tt.want {
+			t.Errorf("layout %+v: got\n%s\nwant\n%s", tt.layout, b.String(), tt.want)
+		}
+	}
+}
+
+func TestPrintTableSeparated(t *testing.T) {
+	rows := []Row{
+		{Proto: "tcp4", Local: "10.0.0.5:51000", Remote: "93.184.216.34:443", State: "ESTABLISHED", PID: 100, Process: "a|b"},
+		{Proto: "tcp6", Local: "[::]:22", Remote: "[::]:0", State: "LISTEN", PID: 7, Process: `"sshd" -D`},
+		{Proto: "tcp4", Local: "10.0.0.5:8080", Remote: "10.0.0.9:40000", State: "ESTABLISHED", PID: 200, Process: `say "hi"`},
+	}
+	opts := Options{ShowHeader: true, Now: time.Date(2026, 1, 2, 3, 4, 5, 0, time.UTC), Title: "T", Separator: "|", Color: true}
+	var b bytes.Buffer
+	PrintTable(&b, rows, opts)
+	want := `T
+Updated:|2026-01-02T03:04:05Z
+PROTO|LOCAL|REMOTE|STATE|PID|PROCESS
+tcp4|10.0.0.5:51000|93.184.216.34:443|ESTABLISHED|100|"a|b"
+tcp4|10.0.0.5:8080|10.0.0.9:40000|ESTABLISHED|200|say "hi"
+tcp6|[::]:22|[::]:0|LISTEN|7|"""sshd"" -D"
+`
+	if b.String() != want {
+		t.Errorf("got\n%s\nwant\n%s", b.String(), want)
+	}
+}
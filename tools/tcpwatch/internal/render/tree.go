@@ -0,0 +1,120 @@
+package render
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"sort"
+	"strings"
+	"time"
+)
+
+// treeNode is a process and its connections in PrintTree.
+type treeNode struct {
+	pid      int32
+	name     string
+	rows     []Row
+	children []*treeNode
+}
+
+// PrintTree prints rows grouped by process: a header line per process
+// followed by its connections, which line up in columns to the right of the
+// process names. A process with a single connection is printed on one line.
+// Rows with PPID set nest their process under the parent's when the parent
+// has connections too. Connections are sorted like the table; processes by
+// name and PID.
+func PrintTree(w io.Writer, rows []Row, opts Options) {
+	SortRows(rows, opts.SortKey)
+	if opts.LinePrefix != "" {
+		w = &prefixWriter{w: w, prefix: []byte(opts.LinePrefix)}
+	}
+
+	nodes := make(map[int32]*treeNode)
+	var order []*treeNode
+	for _, r := range rows {
+		n, ok := nodes[r.PID]
+		if !ok {
+			n = &treeNode{pid: r.PID, name: strings.TrimSpace(r.Process)}
+			nodes[r.PID] = n
+			order = append(order, n)
+		}
+		n.rows = append(n.rows, r)
+	}
+	// A stale PPID can make processes each other's ancestors; such a link
+	// is dropped so that neither goes missing from the output.
+	var roots []*treeNode
+	parents := make(map[*treeNode]*treeNode)
+	for _, n := range order {
+		ppid := n.rows[0].PPID
+		if parent, ok := nodes[ppid]; ok && ppid > 0 && !isAncestor(n, parent, parents) {
+			parents[n] = parent
+			parent.children = append(parent.children, n)
+		} else {
+			roots = append(roots, n)
+		}
+	}
+
+	if opts.Title != "" {
+		fmt.Fprintln(w, opts.Title)
+	}
+	if !opts.Now.IsZero() {
+		fmt.Fprintf(w, "Updated:  %s\n", opts.Now.Format(time.RFC3339))
+	}
+	// Process header lines have empty connection cells so every line stays
+	// in one column block; the padding they leave is trimmed.
+	var buf bytes.Buffer
+	tw := newTabWriter(&buf, opts.Layout)
+	if opts.ShowHeader {
+		fmt.Fprintln(tw, "PROCESS\tPROTO\tLOCAL\tREMOTE\tSTATE")
+	}
+	printTreeNodes(tw, roots, "", opts)
+	_ = tw.Flush()
+	for _, line := range strings.SplitAfter(buf.String(), "\n") {
+		if line != "" {
+			fmt.Fprintln(w, strings.TrimRight(line, " \n"))
+		}
+	}
+	if len(rows) == 0 && opts.EmptyMessage != "" {
+		fmt.Fprintln(w, opts.EmptyMessage)
+	}
+}
+
+// isAncestor reports whether a is n or one of its ancestors in parents.
+func isAncestor(a, n *treeNode, parents map[*treeNode]*treeNode) bool {
+	for ; n != nil; n = parents[n] {
+		if n == a {
+			return true
+		}
+	}
+	return false
+}
+
+func printTreeNodes(w io.Writer, nodes []*treeNode, indent string, opts Options) {
+	sort.Slice(nodes, func(i, j int) bool {
+		if nodes[i].name != nodes[j].name {
+			return nodes[i].name < nodes[j].name
+		}
+		return nodes[i].pid < nodes[j].pid
+	})
+	for _, n := range nodes {
+		label := fmt.Sprintf("%s%s (pid %d)", indent, dash(n.name), n.pid)
+		if len(n.rows) == 1 && len(n.children) == 0 {
+			fmt.Fprintf(w, "%s\t%s\n", label, treeLine(n.rows[0], opts))
+			continue
+		}
+		count := "1 connection"
+		if len(n.rows) != 1 {
+			count = fmt.Sprintf("%d connections", len(n.rows))
+		}
+		fmt.Fprintf(w, "%s, %s\t\t\t\t\n", label, count)
+		for _, r := range n.rows {
+			fmt.Fprintf(w, "\t%s\n", treeLine(r, opts))
+		}
+		printTreeNodes(w, n.children, indent+"  ", opts)
+	}
+}
+
+// treeLine renders a connection of a tree: proto, local, remote and state.
+func treeLine(r Row, opts Options) string {
+	return strings.Join([]string{r.Proto, r.Local, remoteLabel(r, opts), stateLabel(r, opts)}, "\t")
+}
@@ -0,0 +1,60 @@
+package render
+
+import (
+	"bytes"
+	"testing"
+)
+
+func TestPrintTree(t *testing.T) {
+	conn := func(pid, ppid int32, process, local, remote, state string) Row {
+		return Row{Proto: "tcp4", Local: local, Remote: remote, State: state, PID: pid, PPID: ppid, Process: process}
+	}
+	tests := []struct {
+		name string
+		rows []Row
+		want string
+	}{
+		{"several connections", []Row{
+			conn(10, 1, "nginx", "10.0.0.5:443", "192.0.2.1:50000", "ESTABLISHED"),
+			conn(10, 1, "nginx", "0.0.0.0:443", "0.0.0.0:0", "LISTEN"),
+			conn(20, 1, "curl", "10.0.0.5:40000", "192.0.2.9:80", "ESTABLISHED"),
+		}, `PROCESS                        PROTO  LOCAL           REMOTE           STATE
+curl (pid 20)                  tcp4   10.0.0.5:40000  192.0.2.9:80     ESTABLISHED
+nginx (pid 10), 2 connections
+                               tcp4   10.0.0.5:443    192.0.2.1:50000  ESTABLISHED
+                               tcp4   0.0.0.0:443     0.0.0.0:0        LISTEN
+`},
+		{"nested child", []Row{
+			conn(10, 1, "nginx", "0.0.0.0:443", "0.0.0.0:0", "LISTEN"),
+			conn(11, 10, "nginx-worker", "10.0.0.5:443", "192.0.2.1:50000", "ESTABLISHED"),
+			conn(11, 10, "nginx-worker", "10.0.0.5:443", "192.0.2.2:50000", "ESTABLISHED"),
+		}, `PROCESS                                 PROTO  LOCAL         REMOTE           STATE
+nginx (pid 10), 1 connection
+                                        tcp4   0.0.0.0:443   0.0.0.0:0        LISTEN
+  nginx-worker (pid 11), 2 connections
+                                        tcp4   10.0.0.5:443  192.0.2.1:50000  ESTABLISHED
+                                        tcp4   10.0.0.5:443  192.0.2.2:50000  ESTABLISHED
+`},
+		{"missing parent", []Row{
+			conn(11, 10, "nginx-worker", "10.0.0.5:443", "192.0.2.1:50000", "ESTABLISHED"),
+		}, `PROCESS                PROTO  LOCAL         REMOTE           STATE
+nginx-worker (pid 11)  tcp4   10.0.0.5:443  192.0.2.1:50000  ESTABLISHED
+`},
+		// Each names the other as parent; both must still be printed.
+		{"parent cycle", []Row{
+			conn(10, 11, "a", "10.0.0.5:1000", "192.0.2.1:80", "ESTABLISHED"),
+			conn(11, 10, "b", "10.0.0.5:2000", "192.0.2.1:80", "ESTABLISHED"),
+		}, `PROCESS                   PROTO  LOCAL          REMOTE        STATE
+b (pid 11), 1 connection
+                          tcp4   10.0.0.5:2000  192.0.2.1:80  ESTABLISHED
+  a (pid 10)              tcp4   10.0.0.5:1000  192.0.2.1:80  ESTABLISHED
+`},
+	}
+	for _, tt := range tests {
+		var b bytes.Buffer
+		PrintTree(&b, tt.rows, Options{ShowHeader: true})
+		if b.String() != tt.want {
+			t.Errorf("%s: got\n%s\nwant\n%s", tt.name, b.String(), tt.want)
+		}
+	}
+}
@@ -0,0 +1,87 @@
+package render
+
+import (
+	"strings"
+	"unicode/utf8"
+)
+
+// minColumnWidth is the narrowest fitWidth shrinks a column to.
+const minColumnWidth = 3
+
+// fitWidth truncates cells so that the table, laid out by tabwriter with pad
+// spaces between columns, is at most width characters wide. Columns shrink
+// in proportion to their natural width, but never below minColumnWidth, so
+// very narrow widths can still be exceeded. The first column is at least
+// first characters wide: lines outside table (e.g. "Updated:") share it.
+func fitWidth(table [][]string, width, pad, first int) {
+	if len(table) == 0 {
+		return
+	}
+	widths := make([]int, len(table[0]))
+	widths[0] = first
+	for _, line := range table {
+		for i, c := range line {
+			widths[i] = max(widths[i], utf8.RuneCountInString(c))
+		}
+	}
+	total := 0
+	for _, w := range widths {
+		total += w
+	}
+	avail := width - pad*(len(widths)-1)
+	if total <= avail {
+		return
+	}
+	avail = max(avail, 0)
+	w0 := widths[0]
+	widths[0] = max(first, min(w0, minColumnWidth), w0*avail/total)
+	avail, total = max(avail-widths[0], 0), total-w0
+	for i, w := range widths[1:] {
+		widths[i+1] = max(min(w, minColumnWidth), w*avail/max(total, 1))
+	}
+	for _, line := range table {
+		for i, c := range line {
+			line[i] = truncate(c, widths[i])
+		}
+	}
+}
+
+// widthAfterPrefix is the width left for the table once prefix, written at
+// the start of every line (Options.LinePrefix), is taken off. It is at least
+// 1 so that a positive width keeps truncating.
+func widthAfterPrefix(width int, prefix string) int {
+	return max(width-utf8.RuneCountInString(prefix), 1)
+}
+
+// truncate shortens s to n characters, marking the cut with an ellipsis.
+func truncate(s string, n int) string {
+	if utf8.RuneCountInString(s) <= n {
+		return s
+	}
+	r := []rune(s)
+	if n <= 1 {
+		return string(r[:n])
+	}
+	return string(r[:n-1]) + "…"
+}
+
+// truncateMiddle shortens s to n characters by cutting out its middle, so
+// both ends stay visible: for an address the start and the ":port" suffix,
+// e.g. "2001:db8:…:443".
+func truncateMiddle(s string, n int) string {
+	if utf8.RuneCountInString(s) <= n {
+		return s
+	}
+	if n < 5 {
+		return truncate(s, n)
+	}
+	r := []rune(s)
+	tail := n / 2
+	if i := strings.LastIndexByte(s, ':'); i >= 0 {
+		if port := utf8.RuneCountInString(s[i:]); port < tail {
+			tail = port
+		}
+	}
+	head := n - 1 - tail
+	return string(r[:head]) + "…" + string(r[len(r)-tail:])
+}
@@ -0,0 +1,43 @@
+package render
+
+import (
+	"bytes"
+	"io"
+	"slices"
+	"strings"
+	"testing"
+	"time"
+	"unicode/utf8"
+)
+
+// With LinePrefix set, -width still bounds the whole line, prefix included.
+func TestWidthIncludesLinePrefix(t *testing.T) {
+	rows := []Row{
+		{Proto: "tcp4", Local: "10.0.0.5:51000", Remote: "93.184.216.34:443", State: "ESTABLISHED", PID: 100, Process: "curl", Dir: "out"},
+		{Proto: "tcp6", Local: "[::]:22", Remote: "[::]:0", State: "LISTEN", PID: 7, Process: "sshd", Dir: "listen"},
+	}
+	opts := Options{
+		ShowHeader: true,
+		Now:        time.Date(2026, 1, 2, 3, 4, 5, 0, time.UTC),
+		Width:      50,
+		LinePrefix: "web-01 | ",
+	}
+	for name, print := range map[string]func(io.Writer, []Row, Options){
+		"table":     PrintTable,
+		"sectioned": PrintSectioned,
+	} {
+		var b bytes.Buffer
+		print(&b, slices.Clone(rows), opts)
+		if !strings.Contains(b.String(), "…") {
+			t.Errorf("%s: nothing was truncated:\n%s", name, b.String())
+		}
+		for _, line := range strings.Split(strings.TrimSuffix(b.String(), "\n"), "\n") {
+			if !strings.HasPrefix(line, opts.LinePrefix) {
+				t.Errorf("%s: line %q lacks the prefix", name, line)
+			}
+			if n := utf8.RuneCountInString(line); n > opts.Width {
+				t.Errorf("%s: line %q is %d wide, want at most %d", name, line, n, opts.Width)
+			}
+		}
+	}
+}
@@ -0,0 +1,54 @@
+package main
+
+import (
+	"io"
+	"time"
+
+	"github.com/bulent/morzer/tools/tcpwatch/internal/render"
+)
+
+// eventNewListener is the -watch-new-listener event kind.
+const eventNewListener = "new-listener"
+
+// listenerKey identifies a listening socket and its owner, so another
+// process taking over a port (even one reusing the old PID, told apart by
+// its start time) is a new listener.
+type listenerKey struct {
+	proto   string
+	local   string
+	pid     int32
+	started int64
+}
+
+// printNewListeners reports LISTEN sockets that weren't open on the previous
+// refresh. Listeners seen in the first refresh, and for -baseline after it,
+// are recorded silently so startup doesn't alert on everything already open.
+func printNewListeners(w io.Writer, opts options, st *watchState, rows []render.Row) error {
+	now := time.Now()
+	first := st.listeners == nil
+	if first {
+		st.listenersSince = now
+	}
+	learning := first || now.Sub(st.listenersSince) < opts.baseline
+
+	// Listeners that closed are forgotten, so a port reopened later alerts
+	// again.
+	cur := make(map[listenerKey]struct{})
+	var events []connEvent
+	for _, r := range rows {
+		if !r.Listening {
+			continue
+		}
+		k := listenerKey{proto: r.Proto, local: r.Local, pid: r.PID, started: r.Started}
+		if _, dup := cur[k]; dup {
+			continue
+		}
+		cur[k] = struct{}{}
+		if _, ok := st.listeners[k]; !ok && !learning {
+			events = append(events, connEvent{Updated: now, Kind: eventNewListener, Row: r})
+		}
+	}
+	st.listeners = cur
+
+	return printAlerts(w, opts, events)
+}
@@ -0,0 +1,48 @@
+package main
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+
+	"github.com/bulent/morzer/tools/tcpwatch/internal/render"
+)
+
+func TestNewListeners(t *testing.T) {
+	opts, err := parseFlags([]string{"-watch-new-listener"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	listener := func(local string, pid int32, started int64) render.Row {
+		return render.Row{Proto: "tcp4", Local: local, Remote: "0.0.0.0:0", State: "LISTEN", PID: pid, Started: started, Process: "p", Listening: true}
+	}
+	sshd := listener("0.0.0.0:22", 7, 100)
+	tests := []struct {
+		name string
+		rows []render.Row
+		want []string // local addresses alerted on
+	}{
+		{"startup is learned silently", []render.Row{sshd}, nil},
+		{"a port opens", []render.Row{sshd, listener("0.0.0.0:8080", 200, 100)}, []string{"0.0.0.0:8080"}},
+		{"still open", []render.Row{sshd, listener("0.0.0.0:8080", 200, 100)}, nil},
+		{"it closes", []render.Row{sshd}, nil},
+		{"another process reopens it", []render.Row{sshd, listener("0.0.0.0:8080", 300, 100)}, []string{"0.0.0.0:8080"}},
+		{"a new process reuses the PID", []render.Row{listener("0.0.0.0:22", 7, 200), listener("0.0.0.0:8080", 300, 100)}, []string{"0.0.0.0:22"}},
+	}
+	st := &watchState{}
+	for _, tt := range tests {
+		var b bytes.Buffer
+		if err := printNewListeners(&b, opts, st, tt.rows); err != nil {
+			t.Fatal(err)
+		}
+		var got []string
+		for _, line := range strings.Split(strings.TrimSpace(b.String()), "\n") {
+			if f := strings.Fields(line); len(f) > 5 {
+				got = append(got, f[5])
+			}
+		}
+		if strings.Join(got, ",") != strings.Join(tt.want, ",") {
+			t.Errorf("%s: alerts on %q, want %q\n%s", tt.name, got, tt.want, b.String())
+		}
+	}
+}
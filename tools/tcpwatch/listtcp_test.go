@@ -0,0 +1,174 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"maps"
+	"slices"
+	"syscall"
+	"testing"
+	"time"
+
+	gnet "github.com/shirou/gopsutil/v4/net"
+)
+
+// fakeSource is a connSource serving fixed connections and their process
+// names, so listTCP can be tested without touching the host.
+type fakeSource struct {
+	conns []gnet.ConnectionStat
+	names procNames
+}
+
+func (s fakeSource) Connections(ctx context.Context, kind string) ([]gnet.ConnectionStat, error) {
+	var out []gnet.ConnectionStat
+	for _, c := range s.conns {
+		if (c.Family == syscall.AF_UNIX) == (kind == "unix") {
+			out = append(out, c)
+		}
+	}
+	return out, nil
+}
+
+func (s fakeSource) procNames() procNames {
+	return s.names
+}
+
+func tcpConn(family uint32, local, remote gnet.Addr, status string, pid int32) gnet.ConnectionStat {
+	return gnet.ConnectionStat{Family: family, Type: syscall.SOCK_STREAM, Laddr: local, Raddr: remote, Status: status, Pid: pid}
+}
+
+var testSource = fakeSource{
+	conns: []gnet.ConnectionStat{
+		tcpConn(syscall.AF_INET, gnet.Addr{IP: "10.0.0.5", Port: 51000}, gnet.Addr{IP: "93.184.216.34", Port: 443}, "ESTABLISHED", 100),
+		tcpConn(syscall.AF_INET6, gnet.Addr{IP: "::", Port: 22}, gnet.Addr{IP: "::"}, "LISTEN", 7),
+		tcpConn(syscall.AF_INET, gnet.Addr{IP: "0.0.0.0", Port: 8080}, gnet.Addr{IP: "0.0.0.0"}, "LISTEN", 200),
+		tcpConn(syscall.AF_INET, gnet.Addr{IP: "10.0.0.5", Port: 8080}, gnet.Addr{IP: "10.0.0.9", Port: 40000}, "ESTABLISHED", 200),
+		tcpConn(syscall.AF_INET, gnet.Addr{IP: "10.0.0.5", Port: 51001}, gnet.Addr{IP: "1.1.1.1", Port: 53}, "TIME_WAIT", 0),
+		{Family: syscall.AF_UNIX, Type: syscall.SOCK_STREAM, Laddr: gnet.Addr{IP: "/run/docker.sock"}, Status: "LISTEN", Pid: 300},
+	},
+	names: procNames{100: "curl", 7: "sshd", 200: "node", 300: "dockerd"},
+}
+
+func TestListTCPFilters(t *testing.T) {
+	tests := []struct {
+		name string
+		args []string
+		want []string // "proto local" of the listed rows, in source order
+	}{
+		{"all tcp", nil, []string{"tcp4 10.0.0.5:51000", "tcp6 [::]:22", "tcp4 0.0.0.0:8080", "tcp4 10.0.0.5:8080", "tcp4 10.0.0.5:51001"}},
+		{"state", []string{"-state", "ESTABLISHED"}, []string{"tcp4 10.0.0.5:51000", "tcp4 10.0.0.5:8080"}},
+		{"several states", []string{"-state", "LISTEN,TIME_WAIT"}, []string{"tcp6 [::]:22", "tcp4 0.0.0.0:8080", "tcp4 10.0.0.5:51001"}},
+		{"no listeners", []string{"-listen=false"}, []string{"tcp4 10.0.0.5:51000", "tcp4 10.0.0.5:8080", "tcp4 10.0.0.5:51001"}},
+		{"local port", []string{"-port", "8080"}, []string{"tcp4 0.0.0.0:8080", "tcp4 10.0.0.5:8080"}},
+		{"remote port", []string{"-port", "443"}, []string{"tcp4 10.0.0.5:51000"}},
+		{"pid", []string{"-pid", "200"}, []string{"tcp4 0.0.0.0:8080", "tcp4 10.0.0.5:8080"}},
+		{"pid without rows", []string{"-pid", "999"}, nil},
+		{"process", []string{"-proc", "CURL"}, []string{"tcp4 10.0.0.5:51000"}},
+		{"several processes", []string{"-proc", "ssh,node"}, []string{"tcp6 [::]:22", "tcp4 0.0.0.0:8080", "tcp4 10.0.0.5:8080"}},
+		{"unix family", []string{"-proto", "unix"}, []string{"unix /run/docker.sock"}},
+		{"all families", []string{"-proto", "all", "-state", "LISTEN"}, []string{"tcp6 [::]:22", "tcp4 0.0.0.0:8080", "unix /run/docker.sock"}},
+		{"combined", []string{"-state", "ESTABLISHED", "-proc", "node", "-port", "8080"}, []string{"tcp4 10.0.0.5:8080"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			opts, err := parseFlags(tt.args)
+			if err != nil {
+				t.Fatalf("parseFlags(%q): %v", tt.args, err)
+			}
+			opts.source = testSource
+			rows, err := listTCP(context.Background(), opts, newProcResolver(time.Minute, opts.procCacheSize, opts.resolveMethod))
+			if err != nil {
+				t.Fatalf("listTCP: %v", err)
+			}
+			var got []string
+			for _, r := range rows {
+				got = append(got, r.Proto+" "+r.Local)
+			}
+			if !slices.Equal(got, tt.want) {
+				t.Errorf("got %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+// benchSource returns n connections over a mix of IPv4 and IPv6 addresses
+// and a few dozen processes, like a busy server.
+func benchSource(n int) fakeSource {
+	src := fakeSource{names: make(procNames)}
+	for i := range n {
+		pid := int32(1000 + i%50)
+		src.names[pid] = fmt.Sprintf("Worker-%d", pid)
+		port := uint32(1024 + i%60000)
+		c := tcpConn(syscall.AF_INET, gnet.Addr{IP: "10.0.0.5", Port: 443}, gnet.Addr{IP: fmt.Sprintf("192.0.2.%d", i%250), Port: port}, "ESTABLISHED", pid)
+		if i%3 == 0 {
+			c = tcpConn(syscall.AF_INET6, gnet.Addr{IP: "2001:db8::5", Port: 443}, gnet.Addr{IP: fmt.Sprintf("2001:db8::%x", i), Port: port}, "ESTABLISHED", pid)
+		}
+		src.conns = append(src.conns, c)
+	}
+	return src
+}
+
+func BenchmarkListTCP(b *testing.B) {
+	opts, err := parseFlags([]string{"-proc", "WORKER"})
+	if err != nil {
+		b.Fatal(err)
+	}
+	opts.source = benchSource(10000)
+	procs := newProcResolver(time.Minute, opts.procCacheSize, opts.resolveMethod)
+	ctx := context.Background()
+	b.ReportAllocs()
+	for b.Loop() {
+		if _, err := listTCP(ctx, opts, procs); err != nil {
+			b.Fatal(err)
+		}
+	}
+}
+
+// A listener reported without a state (as some backends do) is still a
+// listener for -ports.
+func TestPortsIncludeStatelessListener(t *testing.T) {
+	opts, err := parseFlags([]string{"-ports"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	opts.source = fakeSource{
+		conns: []gnet.ConnectionStat{
+			tcpConn(syscall.AF_INET, gnet.Addr{IP: "0.0.0.0", Port: 8080}, gnet.Addr{IP: "*"}, "NONE", 200),
+			tcpConn(syscall.AF_INET, gnet.Addr{IP: "10.0.0.5", Port: 8080}, gnet.Addr{IP: "10.0.0.9", Port: 40000}, "ESTABLISHED", 200),
+		},
+		names: procNames{200: "node"},
+	}
+	rows, err := listTCP(context.Background(), listOptions(opts), newProcResolver(time.Minute, opts.procCacheSize, opts.resolveMethod))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(rows) != 1 || rows[0].Local != "0.0.0.0:8080" || !rows[0].Listening {
+		t.Errorf("got %+v, want only the 0.0.0.0:8080 listener", rows)
+	}
+}
+
+func TestShowListenerInboundOnly(t *testing.T) {
+	opts, err := parseFlags([]string{"-show-listener"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	opts.source = fakeSource{
+		conns: []gnet.ConnectionStat{
+			tcpConn(syscall.AF_INET, gnet.Addr{IP: "0.0.0.0", Port: 8080}, gnet.Addr{IP: "0.0.0.0"}, "LISTEN", 200),
+			tcpConn(syscall.AF_INET, gnet.Addr{IP: "10.0.0.5", Port: 8080}, gnet.Addr{IP: "10.0.0.9", Port: 40000}, "ESTABLISHED", 201),
+			tcpConn(syscall.AF_INET, gnet.Addr{IP: "10.0.0.5", Port: 40001}, gnet.Addr{IP: "192.0.2.1", Port: 8080}, "ESTABLISHED", 202),
+		},
+		names: procNames{200: "node", 201: "node", 202: "curl"},
+	}
+	rows, err := listTCP(context.Background(), listOptions(opts), newProcResolver(time.Minute, opts.procCacheSize, opts.resolveMethod))
+	if err != nil {
+		t.Fatal(err)
+	}
+	got := make(map[int32]int32)
+	for _, r := range rows {
+		got[r.PID] = r.ListenerPID
+	}
+	if want := map[int32]int32{200: 0, 201: 200, 202: 0}; !maps.Equal(got, want) {
+		t.Errorf("listener PIDs by PID = %v, want %v", got, want)
+	}
+}
@@ -0,0 +1,111 @@
+package main
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"os/exec"
+	"strconv"
+	"strings"
+	"syscall"
+
+	gnet "github.com/shirou/gopsutil/v4/net"
+)
+
+// lsofOwner is the process lsof reported on a listening port.
+type lsofOwner struct {
+	pid  int32
+	name string
+}
+
+// attributeListeners fills in the owner of TCP listening sockets that came
+// back with PID 0 by asking lsof which process listens on their ports
+// (-lsof-fallback). lsof often sees processes the connection listing
+// couldn't. The owners' names are added to names, which is returned.
+// A port lsof knows nothing about keeps PID 0.
+func attributeListeners(ctx context.Context, conns []gnet.ConnectionStat, names procNames) (procNames, error) {
+	orphan := func(c gnet.ConnectionStat) bool {
+		return c.Pid <= 0 && c.Family != syscall.AF_UNIX && isListener(c, normalizeState(c.Status))
+	}
+	// +c0 lifts lsof's 9-character limit on command names, which would
+	// otherwise break -proc matching.
+	args := []string{"-nP", "+c0", "-sTCP:LISTEN", "-Fpcn"}
+	seen := make(map[uint32]struct{})
+	for _, c := range conns {
+		if _, dup := seen[c.Laddr.Port]; dup || !orphan(c) {
+			continue
+		}
+		seen[c.Laddr.Port] = struct{}{}
+		args = append(args, "-iTCP:"+strconv.Itoa(int(c.Laddr.Port)))
+	}
+	if len(seen) == 0 {
+		return names, nil
+	}
+
+	out, err := exec.CommandContext(ctx, "lsof", args...).Output()
+	if err != nil {
+		// lsof exits 1 when nothing matched, and when some files couldn't be
+		// read; either way whatever it printed is still usable.
+		var exit *exec.ExitError
+		if !errors.As(err, &exit) {
+			return names, fmt.Errorf("-lsof-fallback: %w", err)
+		}
+	}
+
+	owners := parseLsofListeners(out)
+	for i := range conns {
+		c := &conns[i]
+		if !orphan(*c) {
+			continue
+		}
+		if o, ok := owners[c.Laddr.Port]; ok {
+			if names == nil {
+				names = make(procNames)
+			}
+			c.Pid = o.pid
+			names[o.pid] = o.name
+		}
+	}
+	return names, nil
+}
+
+// parseLsofListeners parses `lsof -Fpcn` output into the first process seen
+// on each port. Each process starts with a "p<pid>" line and "c<command>"
+// line, followed by "f<fd>" and "n<address>" lines for its sockets, e.g.
+// "n*:443" or "n[::1]:8080".
+func parseLsofListeners(out []byte) map[uint32]lsofOwner {
+	owners := make(map[uint32]lsofOwner)
+	var cur lsofOwner
+	sc := bufio.NewScanner(bytes.NewReader(out))
+	for sc.Scan() {
+		line := sc.Text()
+		if line == "" {
+			continue
+		}
+		switch v := line[1:]; line[0] {
+		case 'p':
+			pid, err := strconv.ParseInt(v, 10, 32)
+			if err != nil {
+				pid = 0
+			}
+			cur = lsofOwner{pid: int32(pid)}
+		case 'c':
+			cur.name = v
+		case 'n':
+			i := strings.LastIndexByte(v, ':')
+			if i < 0 || cur.pid <= 0 {
+				continue
+			}
+			port, err := strconv.ParseUint(v[i+1:], 10, 16)
+			if err != nil {
+				continue
+			}
+			if _, ok := owners[uint32(port)]; !ok {
+				owners[uint32(port)] = cur
+			}
+		}
+	}
+	return owners
+}
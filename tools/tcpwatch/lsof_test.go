@@ -0,0 +1,41 @@
+package main
+
+import (
+	"maps"
+	"testing"
+)
+
+func TestParseLsofListeners(t *testing.T) {
+	out := []byte(`p412
+cpostgres
+f5
+nlocalhost:5432
+f6
+n[::1]:8080
+p977
+ccom.docker.backend
+f18
+n*:443
+f19
+n*:5432
+pbad
+cnoise
+f3
+n*:9999
+p1200
+cnginx
+f7
+n10.0.0.5:80
+f8
+nnot-an-address
+`)
+	want := map[uint32]lsofOwner{
+		5432: {412, "postgres"}, // the first process seen on a port wins
+		8080: {412, "postgres"},
+		443:  {977, "com.docker.backend"},
+		80:   {1200, "nginx"},
+	}
+	if got := parseLsofListeners(out); !maps.Equal(got, want) {
+		t.Errorf("got %+v, want %+v", got, want)
+	}
+}
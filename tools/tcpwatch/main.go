@@ -1,228 +1,1242 @@
 package main
 
 import (
+	"bufio"
 	"context"
 	"encoding/json"
 	"errors"
 	"flag"
 	"fmt"
-	"net"
+	"io"
+	"net/netip"
 	"os"
-	"os/exec"
 	"os/signal"
-	"path/filepath"
+	"regexp"
 	"runtime"
+	"slices"
 	"strconv"
 	"strings"
+	"sync/atomic"
 	"syscall"
 	"time"
 
 	gnet "github.com/shirou/gopsutil/v4/net"
-	gproc "github.com/shirou/gopsutil/v4/process"
 
 	"github.com/bulent/morzer/tools/tcpwatch/internal/render"
 )
 
 type options struct {
-	interval   time.Duration
-	once       bool
-	noClear    bool
-	jsonOut    bool
-	jsonLines  bool
-	stateAllow map[string]struct{}
-	pidFilter  int32
-	portFilter int
-	procFilter string
-	listen     bool
-	header     bool
+	interval         time.Duration
+	once             bool
+	noClear          bool
+	jsonOut          bool
+	jsonLines        bool
+	stateAllow       map[string]struct{}
+	pidFilter        int32
+	portFilter       int
+	procFilters      []string
+	listen           bool
+	listenOnly       bool // only listeners; set by listOptions for -ports
+	header           bool
+	strictPlat       bool
+	ports            bool
+	collapse         bool
+	backend          string
+	highlight        string
+	color            bool
+	pidCount         bool
+	redact           *redactor
+	step             bool
+	direction        string
+	summaryEvery     time.Duration
+	verbose          bool
+	wait             time.Duration
+	serviceNames     bool
+	layout           render.Layout
+	snapshotFile     string
+	dedup            bool
+	quietErrors      bool
+	matchField       string
+	printSchema      bool
+	events           bool
+	onlyStateChanges bool
+	noLoopback       bool
+	loopbackOnly     bool
+	outputBuffer     int
+	csv              bool
+	csvComment       bool
+	duration         time.Duration
+	syslog           bool
+	syslogAddr       string
+	stateCodes       bool
+	restartOnError   bool
+	sshTarget        string
+	sortKey          string
+	age              bool
+	human            bool
+	unsignedOnly     bool
+	mergeRepeats     bool
+	newListeners     bool
+	exitOnAlert      bool
+	baseline         time.Duration
+	columns          []string
+	aggregate        bool
+	hostLabel        string
+	procCacheSize    int
+	remotePorts      portRanges
+	width            int
+	flagRemoteOver   int
+	resolveMethod    string
+	grep             *regexp.Regexp
+	grepKeepHeader   bool
+	metricsAddr      string
+	openMetrics      bool
+	flushOnSignal    bool
+	countUnique      string
+	uniqueByProc     bool
+	noUnknown        bool
+	sockDiag         bool
+	fdWarnPercent    int
+	influx           bool
+	quiet            bool
+	includeThreads   bool
+	baselineFile     string
+	identity         identity
+	warnPorts        portRanges
+	sample           float64
+	proto            string
+	resolveBudget    time.Duration
+	sectioned        bool
+	diag             bool
+	untilStable      bool
+	stableIntervals  int
+	stableTimeout    time.Duration
+	showListener     bool
+	jsonCompact      bool
+	dns              *dnsCache
+	resolveBoth      bool
+	stuck            time.Duration
+	stuckStates      map[string]struct{}
+	linePrefix       string
+	maxRemoteWidth   int
+	// source replaces gopsutil as the default backend's connection source
+	// when set.
+	source     connSource
+	replayFile string
+	replayLoop bool
+	recordFile string
+	strict     string
+	coalesce   bool
+	reusePort  bool
+	clearRate  time.Duration
+	showCwd    bool
+	// headerOnce prints the table header only on the first refresh
+	// (-no-header-repeat).
+	headerOnce bool
+	groupCIDR  *cidrBits
+	webhook    string
+	separator  string
+	counters   bool
+	// enumTimeout bounds how long listing connections may take per refresh.
+	enumTimeout time.Duration
+	// tree groups the table by process (-tree); pidTree also nests child
+	// processes under their parents (-pid-tree).
+	tree    bool
+	pidTree bool
+	// whoListens is the port -who-listens asks about.
+	whoListens int
+	// growthAlert is the -growth-alert increase of ESTABLISHED connections
+	// over growthWindow; zero disables the check.
+	growthAlert  int
+	growthWindow time.Duration
+	// lsofFallback asks lsof for the owners of listeners reported with
+	// PID 0.
+	lsofFallback bool
+	// loopback, if set, is shown in place of every loopback address
+	// (-normalize-loopback).
+	loopback string
+	// ignoreFile is the -watch-ignore-file path, and ignore its rules.
+	ignoreFile string
+	ignore     *ignoreList
+	// seq numbers connections in the order they were first seen.
+	seq bool
+	// format is the output format (see formats); -json, -jsonl, -csv and
+	// -influx set it too.
+	format string
 }
 
 type jsonSnapshot struct {
-	Updated time.Time    `json:"updated"`
-	Title   string       `json:"title,omitempty"`
-	Rows    []render.Row `json:"rows"`
+	Updated time.Time `json:"updated"`
+	Title   string    `json:"title,omitempty"`
+	Host    string    `json:"host,omitempty"`
+	// Filters is omitted in -aggregate output, which merges snapshots
+	// taken with different filters.
+	Filters *snapshotFilters `json:"filters,omitempty"`
+	Rows    []render.Row     `json:"rows"`
+	// warnCount is the number of -warn-port connections, counted before
+	// -sample.
+	warnCount int
 }
 
-type procCacheEntry struct {
-	name  string
-	until time.Time
+type jsonPortsSnapshot struct {
+	Updated time.Time     `json:"updated"`
+	Title   string        `json:"title,omitempty"`
+	Ports   []render.Port `json:"ports"`
 }
 
-type procResolver struct {
-	ttl   time.Duration
-	cache map[int32]procCacheEntry
+// jsonSharedPortsSnapshot is -ports -reuseport's -jsonl line.
+type jsonSharedPortsSnapshot struct {
+	Updated time.Time           `json:"updated"`
+	Title   string              `json:"title,omitempty"`
+	Ports   []render.SharedPort `json:"ports"`
 }
 
-func newProcResolver(ttl time.Duration) *procResolver {
-	return &procResolver{
-		ttl:   ttl,
-		cache: make(map[int32]procCacheEntry),
-	}
+func main() {
+	os.Exit(run())
 }
 
-func (r *procResolver) Name(ctx context.Context, pid int32) string {
-	if pid <= 0 {
-		return ""
+// run is the program; it returns the exit status, so that its deferred
+// cleanup (such as closing the -record file) runs before main exits.
+func run() int {
+	opts, err := parseFlags(os.Args[1:])
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		return 2
 	}
 
-	if ent, ok := r.cache[pid]; ok && time.Now().Before(ent.until) {
-		return ent.name
+	if opts.printSchema {
+		if err := printSchema(os.Stdout); err != nil {
+			fmt.Fprintln(os.Stderr, err)
+			return 1
+		}
+		return 0
 	}
 
-	name := ""
-	if p, err := gproc.NewProcess(pid); err == nil {
-		if n, err := p.NameWithContext(ctx); err == nil {
-			name = strings.TrimSpace(n)
+	if opts.diag {
+		if err := printDiag(context.Background(), os.Stdout); err != nil {
+			fmt.Fprintln(os.Stderr, err)
+			return 1
 		}
+		return 0
 	}
 
-	if name == "" {
-		if n, err := psComm(ctx, pid); err == nil {
-			name = n
+	if !platformSupported {
+		if opts.strictPlat {
+			fmt.Fprintf(os.Stderr, "tcpwatch: %s is not a supported platform (supported: darwin, linux, windows)\n", runtime.GOOS)
+			return 2
 		}
+		fmt.Fprintf(os.Stderr, "tcpwatch: warning: %s is not a supported platform; connection data may be incomplete and process names missing\n", runtime.GOOS)
 	}
 
-	name = strings.TrimSpace(name)
-	r.cache[pid] = procCacheEntry{name: name, until: time.Now().Add(r.ttl)}
-	return name
-}
+	if opts.unsignedOnly && !codesignSupported {
+		fmt.Fprintf(os.Stderr, "tcpwatch: warning: -unsigned-only is only supported on macOS; ignoring it\n")
+	}
 
-func psComm(ctx context.Context, pid int32) (string, error) {
-	if runtime.GOOS == "windows" {
-		return "", fmt.Errorf("ps fallback not available on Windows")
+	procs := newProcResolver(30*time.Second, opts.procCacheSize, opts.resolveMethod)
+	st := &watchState{}
+
+	if opts.syslog {
+		sink, err := newSyslogSink(opts.syslogAddr)
+		if err != nil {
+			fmt.Fprintln(os.Stderr, err)
+			return 1
+		}
+		defer sink.Close()
+		st.sinks = append(st.sinks, sink)
 	}
-	cmd := exec.CommandContext(ctx, "ps", "-p", fmt.Sprint(pid), "-o", "comm=")
-	out, err := cmd.Output()
-	if err != nil {
-		return "", err
+	if opts.webhook != "" {
+		sink, err := newWebhookSink(opts.webhook, opts.hostLabel)
+		if err != nil {
+			fmt.Fprintln(os.Stderr, err)
+			return 1
+		}
+		st.sinks = append(st.sinks, sink)
+	}
+
+	if opts.replayFile != "" {
+		src, err := loadReplay(opts.replayFile, opts.replayLoop)
+		if err != nil {
+			fmt.Fprintln(os.Stderr, err)
+			return 1
+		}
+		opts.source = src
 	}
-	name := strings.TrimSpace(string(out))
-	if name == "" {
-		return "", fmt.Errorf("ps returned empty comm")
+
+	if opts.ignoreFile != "" {
+		if opts.ignore, err = loadIgnoreList(opts.ignoreFile); err != nil {
+			fmt.Fprintln(os.Stderr, err)
+			return 1
+		}
 	}
-	return filepath.Base(name), nil
-}
 
-func main() {
-	opts, err := parseFlags(os.Args[1:])
-	if err != nil {
-		fmt.Fprintln(os.Stderr, err)
-		os.Exit(2)
+	if opts.recordFile != "" {
+		rec, err := newRecorder(opts.recordFile)
+		if err != nil {
+			fmt.Fprintln(os.Stderr, err)
+			return 1
+		}
+		defer func() {
+			if err := rec.close(); err != nil {
+				fmt.Fprintln(os.Stderr, err)
+			}
+		}()
+		st.recorder = rec
+	}
+
+	if opts.baselineFile != "" {
+		if st.fileBaseline, err = loadBaselineFile(opts.baselineFile, opts.hostLabel, opts.identity); err != nil {
+			fmt.Fprintln(os.Stderr, err)
+			return 1
+		}
+	}
+
+	if opts.metricsAddr != "" {
+		st.metrics = &metricsExporter{openMetrics: opts.openMetrics}
+		if err := serveMetrics(opts.metricsAddr, st.metrics); err != nil {
+			fmt.Fprintln(os.Stderr, err)
+			return 1
+		}
 	}
 
-	procs := newProcResolver(30 * time.Second)
+	// With -output-buffer, output is written through a buffer that is
+	// flushed after every refresh and on shutdown.
+	var out io.Writer = os.Stdout
+	flush := func() {}
+	if opts.outputBuffer > 0 {
+		bw := bufio.NewWriterSize(os.Stdout, opts.outputBuffer)
+		out = bw
+		flush = func() {
+			if err := bw.Flush(); err != nil {
+				fmt.Fprintln(os.Stderr, err)
+			}
+		}
+	}
+	defer flush()
+	defer func() {
+		if err := st.flushRepeats(out); err != nil {
+			fmt.Fprintln(os.Stderr, err)
+		}
+	}()
 
 	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
 	defer stop()
 
-	if opts.once {
-		if err := runOnce(ctx, opts, procs); err != nil {
+	// -duration bounds the whole watch; hitting it ends the loop just like
+	// Ctrl-C does.
+	if opts.duration > 0 && !opts.once {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, opts.duration)
+		defer cancel()
+	}
+
+	if opts.aggregate {
+		if err := runAggregate(ctx, os.Stdin, out, opts, st, flush); err != nil {
+			flush()
+			fmt.Fprintln(os.Stderr, err)
+			return 1
+		}
+		return 0
+	}
+
+	if opts.whoListens > 0 {
+		found, err := printWhoListens(ctx, out, opts, procs)
+		flush()
+		if err != nil {
+			fmt.Fprintln(os.Stderr, err)
+			return 1
+		}
+		if !found {
+			return 1
+		}
+		return 0
+	}
+
+	if opts.untilStable {
+		stable, err := waitForStable(ctx, out, opts, procs, st, opts.stableIntervals, opts.stableTimeout)
+		flush()
+		if err != nil {
 			fmt.Fprintln(os.Stderr, err)
-			os.Exit(1)
+			return 1
 		}
-		return
+		if !stable {
+			fmt.Fprintf(os.Stderr, "tcpwatch: connections still changing after %s\n", opts.stableTimeout)
+			return 1
+		}
+		return 0
+	}
+
+	if opts.once && opts.wait > 0 {
+		found, err := waitForRows(ctx, out, opts, procs, st, opts.wait)
+		flush()
+		if err != nil {
+			fmt.Fprintln(os.Stderr, err)
+			return 1
+		}
+		if !found {
+			return 1
+		}
+		return 0
+	}
+
+	if opts.once {
+		_, err := runOnce(ctx, out, opts, procs, st)
+		flush()
+		if err != nil {
+			if !errors.Is(err, errAlert) {
+				fmt.Fprintln(os.Stderr, err)
+			}
+			return 1
+		}
+		return 0
+	}
+
+	// In -refresh-on-key mode Enter on stdin triggers the next refresh instead of the
+	// ticker; the unused channel stays nil and never fires.
+	var tick <-chan time.Time
+	var keys <-chan struct{}
+	if opts.step {
+		keys = readKeys(os.Stdin)
+	} else {
+		ticker := time.NewTicker(opts.interval)
+		defer ticker.Stop()
+		tick = ticker.C
+	}
+
+	// -flush-on-signal dumps the latest snapshot to a file on SIGUSR2 without
+	// disturbing the refresh cycle.
+	var last atomic.Pointer[jsonSnapshot]
+	if sigs := dumpSignals(); opts.flushOnSignal && len(sigs) > 0 {
+		dump := make(chan os.Signal, 1)
+		signal.Notify(dump, sigs...)
+		defer signal.Stop(dump)
+		go func() {
+			for range dump {
+				snap := last.Load()
+				if snap == nil {
+					continue
+				}
+				if path, err := dumpSnapshot(".", *snap); err != nil {
+					fmt.Fprintln(os.Stderr, err)
+				} else if opts.verbose {
+					fmt.Fprintf(os.Stderr, "tcpwatch: wrote %s\n", path)
+				}
+			}
+		}()
+	}
+
+	// SIGHUP rereads -watch-ignore-file; the next refresh uses the new rules.
+	if sigs := reloadSignals(); opts.ignore != nil && len(sigs) > 0 {
+		reload := make(chan os.Signal, 1)
+		signal.Notify(reload, sigs...)
+		defer signal.Stop(reload)
+		go func() {
+			for range reload {
+				if err := opts.ignore.reload(); err != nil {
+					fmt.Fprintf(os.Stderr, "%v (keeping the previous rules)\n", err)
+				} else if opts.verbose {
+					fmt.Fprintf(os.Stderr, "tcpwatch: reloaded %s\n", opts.ignoreFile)
+				}
+			}
+		}()
 	}
 
+	lastSummary := time.Now()
+	slow := slowRefreshes{interval: opts.interval}
+	lastErr := ""
+	failures := 0
+	for {
+		start := time.Now()
+		rows, err := runOnce(ctx, out, opts, procs, st)
+		cost := time.Since(start)
+		if opts.verbose {
+			fmt.Fprintf(os.Stderr, "tcpwatch: refresh took %s\n", cost.Round(time.Millisecond))
+		}
+		if !opts.step && slow.observe(cost) {
+			fmt.Fprintf(os.Stderr, "tcpwatch: warning: refreshes take about %s, longer than -interval %s; consider a larger -interval\n", cost.Round(time.Millisecond), opts.interval)
+		}
+		if err != nil {
+			if errors.Is(err, context.Canceled) || errors.Is(err, context.DeadlineExceeded) || errors.Is(err, errReplayDone) {
+				return 0
+			}
+			if errors.Is(err, errAlert) {
+				flush()
+				return 1
+			}
+			// With -quiet-errors an error is only logged when it differs
+			// from the previous refresh's.
+			if !opts.quietErrors || err.Error() != lastErr {
+				fmt.Fprintln(os.Stderr, err)
+			}
+			lastErr = err.Error()
+
+			failures++
+			if opts.restartOnError && failures >= restartAfterFailures {
+				if opts.verbose {
+					fmt.Fprintf(os.Stderr, "tcpwatch: %d consecutive refreshes failed; restarting in %s\n", failures, restartDelay)
+				}
+				procs = newProcResolver(30*time.Second, opts.procCacheSize, opts.resolveMethod)
+				st.reset()
+				failures = 0
+				select {
+				case <-ctx.Done():
+					return 0
+				case <-time.After(restartDelay):
+				}
+			}
+		} else {
+			lastErr = ""
+			failures = 0
+			last.Store(&jsonSnapshot{Updated: start, Title: "Live TCP connections", Host: opts.hostLabel, Filters: filtersOf(opts), Rows: rows})
+			if opts.summaryEvery > 0 && time.Since(lastSummary) >= opts.summaryEvery {
+				lastSummary = time.Now()
+				if err := writeSummary(out, rows, lastSummary); err != nil {
+					fmt.Fprintln(os.Stderr, err)
+				}
+			}
+		}
+		flush()
+		if opts.step && !opts.jsonOut && !opts.jsonLines {
+			fmt.Fprintln(os.Stderr, "Press Enter to refresh (Ctrl-C to quit)")
+		}
+
+		select {
+		case <-ctx.Done():
+			return 0
+		case <-tick:
+		case _, ok := <-keys:
+			if !ok {
+				return 0
+			}
+		}
+	}
+}
+
+// With -restart-on-error, this many consecutive failed refreshes make the
+// watch loop drop its resolver and caches and start over after restartDelay.
+const (
+	restartAfterFailures = 3
+	restartDelay         = 5 * time.Second
+)
+
+// slowRefreshesWarnAfter is how many consecutive refreshes must overrun the
+// interval before warning, so a single slow refresh doesn't trigger it.
+const slowRefreshesWarnAfter = 3
+
+// slowRefreshes detects refreshes that consistently take longer than the
+// interval, in which case ticks are dropped and the interval isn't honored.
+type slowRefreshes struct {
+	interval time.Duration
+	streak   int
+	warned   bool
+}
+
+// observe records a refresh duration and reports whether to warn now. It
+// reports true at most once.
+func (s *slowRefreshes) observe(cost time.Duration) bool {
+	if cost <= s.interval {
+		s.streak = 0
+		return false
+	}
+	s.streak++
+	if s.warned || s.streak < slowRefreshesWarnAfter {
+		return false
+	}
+	s.warned = true
+	return true
+}
+
+// readKeys sends on the returned channel for every line read from r and
+// closes it at EOF.
+func readKeys(r io.Reader) <-chan struct{} {
+	ch := make(chan struct{})
+	go func() {
+		defer close(ch)
+		sc := bufio.NewScanner(r)
+		for sc.Scan() {
+			ch <- struct{}{}
+		}
+	}()
+	return ch
+}
+
+// runOnce lists connections and prints them in the selected output mode. It
+// returns the listed rows.
+func runOnce(ctx context.Context, w io.Writer, opts options, procs *procResolver, st *watchState) ([]render.Row, error) {
+	rows, err := listTCP(ctx, listOptions(opts), procs)
+	if err != nil {
+		return nil, err
+	}
+	// Names left out by -resolve-budget say nothing about privileges.
+	if localPIDs(opts) && !procs.budgetHit {
+		st.privilegeHint(os.Stderr, rows)
+	}
+	if st.metrics != nil {
+		st.metrics.update(rows, time.Now())
+	}
+	if opts.growthAlert > 0 {
+		st.checkGrowth(os.Stderr, rows, opts, time.Now())
+	}
+	if opts.flagRemoteOver > 0 {
+		flagBusyRemotes(rows, opts.flagRemoteOver)
+	}
+	if opts.age {
+		st.setAges(rows, opts.identity, time.Now())
+	}
+	if opts.seq {
+		st.setSeqs(rows, opts.identity)
+	}
+	if opts.stuck > 0 {
+		rows = st.stuckRows(rows, opts, time.Now())
+	}
+	if err := saveSnapshot(opts, rows); err != nil {
+		return rows, err
+	}
+	if st.recorder != nil {
+		if err := st.recorder.write(rows); err != nil {
+			return rows, err
+		}
+	}
+	w = st.startRefresh(w, opts)
+	defer st.endRefresh(w)
+	return rows, printRows(w, opts, st, rows)
+}
+
+// saveSnapshot writes rows to -snapshot-file, if set.
+func saveSnapshot(opts options, rows []render.Row) error {
+	if opts.snapshotFile == "" {
+		return nil
+	}
+	// In the order printRows gives every format, so the file matches -json
+	// and doesn't reorder between refreshes.
+	render.SortRows(rows, opts.sortKey)
+	snap := jsonSnapshot{Updated: time.Now(), Title: "Live TCP connections", Host: opts.hostLabel, Filters: filtersOf(opts), Rows: rows}
+	if err := writeSnapshotFile(opts.snapshotFile, snap); err != nil {
+		return fmt.Errorf("write -snapshot-file: %w", err)
+	}
+	return nil
+}
+
+// listOptions adjusts the filters for report modes that need specific rows.
+func listOptions(opts options) options {
+	if opts.ports {
+		opts.listen, opts.listenOnly = true, true
+		opts.stateAllow = nil
+	}
+	if opts.whoListens > 0 {
+		opts.listen, opts.listenOnly = true, true
+		opts.stateAllow = nil
+		opts.portFilter = opts.whoListens
+	}
+	return opts
+}
+
+// waitForRows polls like the watch loop until the filters match at least one
+// row or the timeout elapses, then prints the last result. It reports
+// whether anything matched.
+func waitForRows(ctx context.Context, w io.Writer, opts options, procs *procResolver, st *watchState, timeout time.Duration) (bool, error) {
+	ctx, cancel := context.WithTimeout(ctx, timeout)
+	defer cancel()
+
 	ticker := time.NewTicker(opts.interval)
 	defer ticker.Stop()
 
 	for {
-		if err := runOnce(ctx, opts, procs); err != nil {
-			if errors.Is(err, context.Canceled) {
-				return
+		rows, err := listTCP(ctx, listOptions(opts), procs)
+		if err != nil && ctx.Err() == nil {
+			return false, err
+		}
+		if len(rows) > 0 {
+			if err := saveSnapshot(opts, rows); err != nil {
+				return true, err
 			}
-			fmt.Fprintln(os.Stderr, err)
+			return true, printRows(w, opts, st, rows)
 		}
 
 		select {
 		case <-ctx.Done():
-			return
+			rows = []render.Row{}
+			if err := saveSnapshot(opts, rows); err != nil {
+				return false, err
+			}
+			return false, printRows(w, opts, st, rows)
 		case <-ticker.C:
 		}
 	}
 }
 
-func runOnce(ctx context.Context, opts options, procs *procResolver) error {
-	rows, err := listTCP(ctx, opts, procs)
-	if err != nil {
-		return err
+// waitForStable polls like the watch loop until the connection set (by
+// -identity, including states) is unchanged for n consecutive intervals or
+// the timeout elapses, then prints the last result. It reports whether the
+// set settled.
+func waitForStable(ctx context.Context, w io.Writer, opts options, procs *procResolver, st *watchState, n int, timeout time.Duration) (bool, error) {
+	ctx, cancel := context.WithTimeout(ctx, timeout)
+	defer cancel()
+
+	ticker := time.NewTicker(opts.interval)
+	defer ticker.Stop()
+
+	var prev map[connKey]render.Row
+	last := []render.Row{}
+	unchanged := 0
+	for {
+		rows, err := listTCP(ctx, listOptions(opts), procs)
+		if err != nil {
+			if ctx.Err() != nil {
+				return false, printRows(w, opts, st, last)
+			}
+			return false, err
+		}
+		if prev != nil && len(diffRows(prev, rows, opts.identity, time.Now())) == 0 {
+			unchanged++
+		} else {
+			unchanged = 0
+		}
+		prev, last = rowsByKey(rows, opts.identity), rows
+		if unchanged >= n {
+			return true, printRows(w, opts, st, rows)
+		}
+
+		select {
+		case <-ctx.Done():
+			return false, printRows(w, opts, st, last)
+		case <-ticker.C:
+		}
+	}
+}
+
+// jsonOutEncoder returns the encoder for -json output: indented, or on one
+// line with -json-compact.
+func jsonOutEncoder(w io.Writer, opts options) *json.Encoder {
+	enc := json.NewEncoder(w)
+	if !opts.jsonCompact {
+		enc.SetIndent("", "  ")
+	}
+	return enc
+}
+
+func printRows(w io.Writer, opts options, st *watchState, rows []render.Row) error {
+	// Every format gets the same deterministic order so consecutive JSON
+	// snapshots can be compared with diff or jq.
+	render.SortRows(rows, opts.sortKey)
+
+	if opts.events {
+		return printEvents(w, opts, st, rows)
+	}
+	if st.fileBaseline != nil {
+		return printBaselineDiff(w, opts, st, rows)
+	}
+	if opts.newListeners {
+		return printNewListeners(w, opts, st, rows)
+	}
+	if opts.remotePorts != nil {
+		return printRemotePortAlerts(w, opts, st, rows)
+	}
+	if opts.ports {
+		return printPorts(w, opts, st, rows)
+	}
+	if opts.pidCount {
+		return printPIDCount(w, opts, rows)
+	}
+	if opts.countUnique != "" {
+		return printUniqueRemotes(w, opts, rows)
+	}
+	if opts.groupCIDR != nil {
+		return printSubnetCounts(w, opts, rows)
+	}
+	r := st.renderer
+	if r == nil {
+		r = newRenderer(opts, st)
+		st.renderer = r
+	}
+	snap := jsonSnapshot{
+		Updated: time.Now(),
+		Title:   "Live TCP connections",
+		Host:    opts.hostLabel,
+		Rows:    rows,
+	}
+	if !opts.aggregate {
+		snap.Filters = filtersOf(opts)
+	}
+
+	if opts.warnPorts != nil {
+		// Row.Warn is also set by -per-process-limit, so count by port.
+		for _, row := range snap.Rows {
+			if opts.warnPorts.containsRow(row) {
+				snap.warnCount++
+			}
+		}
+	}
+	// Only the connection listings are sampled; the counting modes above,
+	// the -influx and prometheus formats, alerts and -metrics-addr always
+	// see every row.
+	if opts.sample > 0 && !opts.influx && opts.format != formatPrometheus {
+		snap.Rows = sampleRows(snap.Rows, opts.sample, opts.identity)
+		snap.Title += fmt.Sprintf(" (sample of ~%g%%)", opts.sample*100)
+	}
+	if opts.strict != "" {
+		snap.Rows = validateRows(os.Stderr, snap.Rows, opts.strict, opts.redact != nil)
+	}
+	return r.render(w, snap)
+}
+
+// printPorts prints the open ports report: every LISTEN socket on this host
+// with its binding process, sorted by port.
+func printPorts(w io.Writer, opts options, st *watchState, rows []render.Row) error {
+	ports := render.Ports(rows)
+	if opts.reusePort {
+		return printSharedPorts(w, opts, st, render.SharedPorts(ports))
 	}
 
 	if !opts.noClear && !opts.jsonOut && !opts.jsonLines {
-		fmt.Print("\033[2J\033[H")
+		clearScreen(w, opts, st)
 	}
 
 	if opts.jsonLines {
-		enc := json.NewEncoder(os.Stdout)
-		return enc.Encode(jsonSnapshot{
+		enc := json.NewEncoder(w)
+		return enc.Encode(jsonPortsSnapshot{
 			Updated: time.Now(),
-			Title:   "Live TCP connections",
-			Rows:    rows,
+			Title:   "Open ports",
+			Ports:   ports,
 		})
 	}
 
 	if opts.jsonOut {
-		enc := json.NewEncoder(os.Stdout)
-		enc.SetIndent("", "  ")
-		return enc.Encode(rows)
+		enc := jsonOutEncoder(w, opts)
+		return enc.Encode(ports)
 	}
 
-	render.PrintTable(os.Stdout, rows, render.Options{
+	render.PrintPorts(w, ports, render.Options{
 		ShowHeader: opts.header,
 		Now:        time.Now(),
-		Title:      "Live TCP connections",
+		Title:      "Open ports",
+		Layout:     opts.layout,
 	})
 	return nil
 }
 
-func listTCP(ctx context.Context, opts options, procs *procResolver) ([]render.Row, error) {
-	// gopsutil uses sysctl on macOS to retrieve connection data.
-	conns, err := gnet.ConnectionsWithContext(ctx, "tcp")
-	if err != nil {
-		return nil, err
+// printSharedPorts is printPorts for -reuseport.
+func printSharedPorts(w io.Writer, opts options, st *watchState, ports []render.SharedPort) error {
+	const title = "Open ports by sharing processes"
+	if !opts.noClear && !opts.jsonOut && !opts.jsonLines {
+		clearScreen(w, opts, st)
+	}
+
+	if opts.jsonLines {
+		enc := json.NewEncoder(w)
+		return enc.Encode(jsonSharedPortsSnapshot{
+			Updated: time.Now(),
+			Title:   title,
+			Ports:   ports,
+		})
+	}
+
+	if opts.jsonOut {
+		enc := jsonOutEncoder(w, opts)
+		return enc.Encode(ports)
+	}
+
+	render.PrintSharedPorts(w, ports, render.Options{
+		ShowHeader: opts.header,
+		Now:        time.Now(),
+		Title:      title,
+		Layout:     opts.layout,
+	})
+	return nil
+}
+
+func listTCP(ctx context.Context, opts options, procs *procResolver) ([]render.Row, error) {
+	conns, names, err := connections(ctx, opts)
+	if err != nil {
+		return nil, err
+	}
+	if opts.lsofFallback {
+		if names, err = attributeListeners(ctx, conns, names); err != nil {
+			return nil, err
+		}
+	}
+
+	var listening map[uint32]struct{}
+	if opts.direction != "" || opts.serviceNames || opts.showListener {
+		listening = listenPorts(conns)
+	}
+
+	var socks map[sockKey]sockInfo
+	if opts.sockDiag || opts.counters {
+		if socks, err = sockDiag(opts.counters); err != nil {
+			return nil, err
+		}
+	}
+
+	// -per-process-limit counts every connection of a process, not only the
+	// ones that pass the filters.
+	var connsByPID map[int32]int
+	if opts.fdWarnPercent > 0 {
+		connsByPID = make(map[int32]int)
+		for _, c := range conns {
+			connsByPID[c.Pid]++
+		}
+	}
+
+	var accept acceptors
+	if opts.showListener {
+		accept = findAcceptors(conns)
+	}
+
+	// The -proc filters are compared case-insensitively; lowercase them once.
+	procFilters := make([]string, len(opts.procFilters))
+	for i, f := range opts.procFilters {
+		procFilters[i] = strings.ToLower(f)
+	}
+
+	// Process start times are looked up once per PID per refresh.
+	starts := make(map[int32]int64)
+	// So are thread group IDs for -proc-include-threads.
+	tgids := make(map[int32]int32)
+
+	// resolveName looks up process names until -resolve-budget is used up;
+	// after that only cached names are shown for the rest of the refresh.
+	var resolving time.Duration
+	procs.budgetHit = false
+	resolveName := func(pid int32) string {
+		if name, ok := procs.CachedName(pid); ok {
+			return name
+		}
+		if opts.resolveBudget > 0 && resolving >= opts.resolveBudget {
+			procs.budgetHit = true
+			return ""
+		}
+		start := time.Now()
+		name := procs.Name(ctx, pid)
+		resolving += time.Since(start)
+		return name
+	}
+
+	rows := make([]render.Row, 0, len(conns))
+	for _, c := range conns {
+		state := normalizeState(c.Status)
+		unix := c.Family == syscall.AF_UNIX
+		if unix && (state == "NONE" || state == "UNKNOWN") {
+			// Unix sockets that aren't stream connections have no state.
+			state = "-"
+		}
+		listener := isListener(c, state)
+		if !opts.listen && listener || opts.listenOnly && !listener {
+			continue
+		}
+		if opts.noUnknown && state == "UNKNOWN" {
+			continue
+		}
+		if len(opts.stateAllow) > 0 {
+			if _, ok := opts.stateAllow[state]; !ok {
+				continue
+			}
+		}
+		if opts.pidFilter >= 0 && c.Pid != opts.pidFilter {
+			continue
+		}
+		if opts.portFilter > 0 {
+			if int(c.Laddr.Port) != opts.portFilter && int(c.Raddr.Port) != opts.portFilter {
+				continue
+			}
+		}
+		if opts.noLoopback || opts.loopbackOnly {
+			if isLoopbackConn(c) != opts.loopbackOnly {
+				continue
+			}
+		}
+
+		dir := ""
+		if listening != nil && !unix {
+			dir = direction(c, listener, listening)
+			if opts.direction != "" && opts.direction != "all" && dir != opts.direction {
+				continue
+			}
+		}
+
+		// Names reported by an external backend win; for a remote host they
+		// are the only option since its PIDs mean nothing locally.
+		procName, ok := names[c.Pid]
+		if !ok && localPIDs(opts) {
+			procName = resolveName(c.Pid)
+		}
+		// With -proc-include-threads a PID that turns out to be a thread ID
+		// is named after its process (the thread group leader).
+		var tgid int32
+		if opts.includeThreads && localPIDs(opts) && c.Pid > 0 {
+			var seen bool
+			if tgid, seen = tgids[c.Pid]; !seen {
+				tgid = threadGroup(c.Pid)
+				tgids[c.Pid] = tgid
+			}
+			if tgid == c.Pid {
+				tgid = 0
+			}
+			if tgid > 0 {
+				if name, ok := names[tgid]; ok {
+					procName = name
+				} else {
+					procName = resolveName(tgid)
+				}
+			}
+		}
+		if opts.ignore != nil && opts.ignore.ignores(c, procName) {
+			continue
+		}
+		if len(procFilters) > 0 {
+			field := procName
+			switch opts.matchField {
+			case "exe":
+				field = procs.Exe(ctx, c.Pid)
+			case "cmdline":
+				field = procs.Cmdline(ctx, c.Pid)
+			}
+			if field == "" {
+				continue
+			}
+			field = strings.ToLower(field)
+			if !slices.ContainsFunc(procFilters, func(f string) bool { return strings.Contains(field, f) }) {
+				continue
+			}
+		}
+
+		if opts.unsignedOnly && codesignSupported && !procs.Unsigned(ctx, c.Pid) {
+			continue
+		}
+
+		row := render.Row{
+			Proto:     familyProto(c.Family),
+			Local:     formatAddr(c.Laddr, opts.loopback),
+			Remote:    formatAddr(c.Raddr, opts.loopback),
+			State:     state,
+			PID:       c.Pid,
+			Process:   procName,
+			TGID:      tgid,
+			Listening: listener,
+		}
+		if unix {
+			row.Local, row.Remote = unixPath(c.Laddr.IP), unixPath(c.Raddr.IP)
+		}
+		// Only inbound connections were accepted; an outbound one can
+		// share a listener's local port on another address.
+		if accept != nil && dir == "in" {
+			if pid, ok := accept.of(c); ok {
+				row.ListenerPID = pid
+				name, ok := names[pid]
+				if !ok && localPIDs(opts) {
+					name = resolveName(pid)
+				}
+				row.Listener = name
+			}
+		}
+		if opts.pidTree && c.Pid > 0 && localPIDs(opts) {
+			row.PPID = procs.PPID(ctx, c.Pid)
+		}
+		if opts.showCwd && c.Pid > 0 && localPIDs(opts) {
+			row.Cwd = procs.Cwd(ctx, c.Pid)
+		}
+		if opts.direction != "" {
+			row.Dir = dir
+		}
+		if opts.serviceNames {
+			row.Service = connService(c.Laddr.Port, c.Raddr.Port, dir)
+		}
+		row.Host = opts.hostLabel
+		if opts.fdWarnPercent > 0 && c.Pid > 0 {
+			row.Conns = connsByPID[c.Pid]
+			if localPIDs(opts) {
+				row.FDLimit = procs.FDLimit(ctx, c.Pid)
+			}
+			row.Warn = row.FDLimit > 0 && uint64(row.Conns)*100 >= row.FDLimit*uint64(opts.fdWarnPercent)
+		}
+		if opts.warnPorts != nil && (opts.warnPorts.contains(c.Laddr.Port) || opts.warnPorts.contains(c.Raddr.Port)) {
+			row.Warn = true
+		}
+		if info, ok := socks[connSockKey(c)]; ok {
+			if opts.sockDiag {
+				row.Inode = info.inode
+				uid := info.uid
+				row.UID = &uid
+			}
+			row.Counters = info.counters
+		}
+		if tracksConnections(opts) && localPIDs(opts) {
+			started, ok := starts[c.Pid]
+			if !ok {
+				started = procs.Started(ctx, c.Pid)
+				starts[c.Pid] = started
+			}
+			row.Started = started
+		}
+		if hasColumn(opts, "family") {
+			row.Family = familyName(c.Family)
+		}
+		if hasColumn(opts, "fd") {
+			row.FD = c.Fd
+		}
+		if hasColumn(opts, "user") && localPIDs(opts) {
+			row.User = procs.User(ctx, c.Pid)
+		}
+		if hasColumn(opts, "exposure") && listener {
+			row.Exposure = exposure(c.Laddr.IP)
+		}
+		if opts.stateCodes {
+			if code, ok := tcpStateCodes[state]; ok {
+				row.StateCode = &code
+			}
+		}
+		rows = append(rows, row)
+	}
+
+	if opts.dedup {
+		rows = dedupLinkLocal(rows)
+	}
+	if opts.coalesce {
+		rows = coalesceListeners(rows)
+	}
+	if opts.dns != nil && opts.sshTarget == "" {
+		for i := range rows {
+			if ip := remoteHost(rows[i].Remote); ip != "" && rows[i].Proto != "unix" {
+				rows[i].RemoteHost = opts.dns.Name(ctx, ip)
+			}
+		}
+	}
+	if opts.redact != nil {
+		for i := range rows {
+			rows[i].Local = opts.redact.Addr(rows[i].Local)
+			rows[i].Remote = opts.redact.Addr(rows[i].Remote)
+		}
+	}
+	return rows, nil
+}
+
+// isListener reports whether c is a listening socket: in state LISTEN, or a
+// TCP socket with no reported state and no remote end, which is how some
+// platforms list listeners.
+func isListener(c gnet.ConnectionStat, state string) bool {
+	if state == "LISTEN" {
+		return true
+	}
+	if c.Family == syscall.AF_UNIX || (state != "UNKNOWN" && state != "NONE") || c.Raddr.Port != 0 {
+		return false
+	}
+	if c.Raddr.IP == "" || c.Raddr.IP == "*" {
+		return true
+	}
+	ip, err := netip.ParseAddr(c.Raddr.IP)
+	return err == nil && ip.IsUnspecified()
+}
+
+// listenPorts returns the local ports of all listening sockets.
+func listenPorts(conns []gnet.ConnectionStat) map[uint32]struct{} {
+	ports := make(map[uint32]struct{})
+	for _, c := range conns {
+		if isListener(c, normalizeState(c.Status)) {
+			ports[c.Laddr.Port] = struct{}{}
+		}
+	}
+	return ports
+}
+
+// direction classifies a connection as inbound when its local port is one
+// we listen on (a remote connected to us) and outbound otherwise. This is a
+// heuristic: an outbound socket can in theory share a listener's port.
+func direction(c gnet.ConnectionStat, listener bool, listening map[uint32]struct{}) string {
+	if listener {
+		return "listen"
+	}
+	if _, ok := listening[c.Laddr.Port]; ok {
+		return "in"
+	}
+	return "out"
+}
+
+// isLoopbackConn reports whether both endpoints are loopback. Listeners
+// (whose remote is unset or unspecified) count when bound to loopback.
+func isLoopbackConn(c gnet.ConnectionStat) bool {
+	if !isLoopbackIP(c.Laddr.IP) {
+		return false
+	}
+	if c.Raddr.IP == "" {
+		return true
+	}
+	if ip, err := netip.ParseAddr(c.Raddr.IP); err == nil && ip.IsUnspecified() {
+		return true
+	}
+	return isLoopbackIP(c.Raddr.IP)
+}
+
+func isLoopbackIP(s string) bool {
+	ip, err := netip.ParseAddr(s)
+	return err == nil && ip.Unmap().IsLoopback()
+}
+
+// expandLinePrefix returns -line-prefix with %h replaced by the host label
+// (or hostname), %t by the time and %% by a percent sign.
+func expandLinePrefix(opts options, now time.Time) string {
+	if opts.linePrefix == "" {
+		return ""
+	}
+	host := opts.hostLabel
+	if host == "" && strings.Contains(opts.linePrefix, "%h") {
+		host, _ = os.Hostname()
+	}
+	return strings.NewReplacer("%%", "%", "%h", host, "%t", now.Format(time.RFC3339)).Replace(opts.linePrefix)
+}
+
+// emptyMessage is the line shown under a table without rows.
+func emptyMessage(opts options) string {
+	if opts.quiet {
+		return ""
 	}
+	return "(no matching connections)"
+}
 
-	rows := make([]render.Row, 0, len(conns))
-	for _, c := range conns {
-		state := normalizeState(c.Status)
-		if !opts.listen && state == "LISTEN" {
-			continue
-		}
-		if len(opts.stateAllow) > 0 {
-			if _, ok := opts.stateAllow[state]; !ok {
-				continue
-			}
-		}
-		if opts.pidFilter >= 0 && c.Pid != opts.pidFilter {
-			continue
-		}
-		if opts.portFilter > 0 {
-			if int(c.Laddr.Port) != opts.portFilter && int(c.Raddr.Port) != opts.portFilter {
-				continue
-			}
-		}
+// localPIDs reports whether listed PIDs belong to this machine, so process
+// details can be looked up; not so for -ssh and -replay.
+func localPIDs(opts options) bool {
+	return opts.sshTarget == "" && opts.replayFile == ""
+}
 
-		procName := procs.Name(ctx, c.Pid)
-		if opts.procFilter != "" {
-			if procName == "" {
-				continue
-			}
-			if !strings.Contains(strings.ToLower(procName), strings.ToLower(opts.procFilter)) {
-				continue
-			}
-		}
+// tracksConnections reports whether an option follows connections across
+// refreshes and so needs their process start times.
+func tracksConnections(opts options) bool {
+	return opts.events || opts.age || opts.seq || opts.remotePorts != nil || opts.stuck > 0 || opts.newListeners
+}
 
-		rows = append(rows, render.Row{
-			Proto:   familyProto(c.Family),
-			Local:   formatAddr(c.Laddr),
-			Remote:  formatAddr(c.Raddr),
-			State:   state,
-			PID:     c.Pid,
-			Process: procName,
-		})
+// hasColumn reports whether -columns/-preset selected the table column name.
+func hasColumn(opts options, name string) bool {
+	return slices.Contains(opts.columns, name)
+}
+
+func familyName(family uint32) string {
+	switch family {
+	case syscall.AF_INET:
+		return "inet"
+	case syscall.AF_INET6:
+		return "inet6"
+	case syscall.AF_UNIX:
+		return "unix"
+	default:
+		return ""
 	}
+}
 
-	return rows, nil
+// exposure classifies who can reach a socket bound to ip.
+func exposure(ip string) string {
+	if ip == "" {
+		return "any"
+	}
+	addr, err := netip.ParseAddr(ip)
+	switch {
+	case err != nil:
+		return ""
+	case addr.IsUnspecified():
+		return "any"
+	case addr.Unmap().IsLoopback():
+		return "loopback"
+	default:
+		return "iface"
+	}
 }
 
 func familyProto(family uint32) string {
@@ -231,25 +1245,57 @@ func familyProto(family uint32) string {
 		return "tcp4"
 	case syscall.AF_INET6:
 		return "tcp6"
+	case syscall.AF_UNIX:
+		return "unix"
 	default:
 		return "tcp"
 	}
 }
 
-func formatAddr(a gnet.Addr) string {
+// unixPath renders a Unix socket address: its path, or "-" for unnamed
+// sockets.
+func unixPath(path string) string {
+	if path == "" {
+		return "-"
+	}
+	return path
+}
+
+// formatAddr renders a as "ip:port", bracketing IPv6 addresses. A non-empty
+// loopback replaces any loopback IP (-normalize-loopback).
+func formatAddr(a gnet.Addr, loopback string) string {
 	if a.IP == "" && a.Port == 0 {
 		return "*:*"
 	}
 	ip := a.IP
 	if ip == "" {
 		ip = "*"
+	} else if loopback != "" && isLoopbackIP(ip) {
+		ip = loopback
 	}
 
-	parsed := net.ParseIP(ip)
-	if parsed != nil && parsed.To4() == nil {
-		return fmt.Sprintf("[%s]:%d", ip, a.Port)
+	// This runs for both ends of every connection on each refresh, so it
+	// builds the string directly instead of going through fmt. Only
+	// addresses with a colon can be IPv6; netip understands zoned
+	// link-local addresses such as fe80::1%en0, which net.ParseIP rejects.
+	bracket := false
+	if strings.IndexByte(ip, ':') >= 0 {
+		parsed, err := netip.ParseAddr(ip)
+		bracket = err == nil && parsed.Is6() && !parsed.Is4In6()
 	}
-	return fmt.Sprintf("%s:%d", ip, a.Port)
+	var b strings.Builder
+	b.Grow(len(ip) + len("[]:65535"))
+	if bracket {
+		b.WriteByte('[')
+	}
+	b.WriteString(ip)
+	if bracket {
+		b.WriteByte(']')
+	}
+	b.WriteByte(':')
+	var port [5]byte
+	b.Write(strconv.AppendUint(port[:0], uint64(a.Port), 10))
+	return b.String()
 }
 
 func normalizeState(s string) string {
@@ -266,37 +1312,565 @@ func parseFlags(args []string) (options, error) {
 	fs := flag.NewFlagSet("tcpwatch", flag.ContinueOnError)
 	fs.SetOutput(os.Stderr)
 
-	fs.DurationVar(&opts.interval, "interval", 1*time.Second, "Refresh interval (e.g. 500ms, 2s)")
+	opts.interval = 1 * time.Second
+	fs.Var((*secondsDuration)(&opts.interval), "interval", "Refresh interval as a `duration` (e.g. 500ms, 2s; a bare number means seconds)")
+	fs.Var((*secondsDuration)(&opts.interval), "I", "Short for -interval")
 	fs.BoolVar(&opts.once, "once", false, "Print once and exit")
+	fs.DurationVar(&opts.wait, "wait", 0, "With -once, poll until at least one row matches or this timeout elapses (exit 1 if none did)")
+	fs.BoolVar(&opts.untilStable, "watch-until-stable", false, "Poll until the connection set stops changing, print it once and exit (exit 1 on -stable-timeout)")
+	fs.IntVar(&opts.stableIntervals, "stable-intervals", 3, "With -watch-until-stable, how many consecutive intervals without changes count as stable")
+	fs.DurationVar(&opts.stableTimeout, "stable-timeout", time.Minute, "With -watch-until-stable, give up after this long")
+	fs.DurationVar(&opts.duration, "duration", 0, "Stop watching after this much time (e.g. 10m; 0 runs until interrupted)")
+	fs.BoolVar(&opts.step, "refresh-on-key", false, "Refresh when Enter is pressed instead of on a timer")
+	fs.BoolVar(&opts.step, "step", false, "Short for -refresh-on-key")
 	fs.BoolVar(&opts.noClear, "no-clear", false, "Don’t clear the screen between refreshes")
-	fs.BoolVar(&opts.jsonOut, "json", false, "Output as JSON")
-	fs.BoolVar(&opts.jsonLines, "jsonl", false, "Output as NDJSON stream (one JSON object per refresh)")
+	format := fs.String("format", "", "Output `format`: "+strings.Join(formats, ", ")+" (default table; jsonl is one JSON object per refresh, csv has the header once and an updated timestamp per row, tsv is csv with tabs, influx counts by state, protocol and process as InfluxDB line protocol (measurement tcp_connections), prometheus the -metrics-addr text each refresh)")
+	fs.BoolVar(&opts.jsonOut, "json", false, "Deprecated: use -format json")
+	fs.BoolVar(&opts.jsonLines, "jsonl", false, "Deprecated: use -format jsonl")
+	fs.DurationVar(&opts.summaryEvery, "summary-every", 0, "With -jsonl, interleave a summary object (counts by state/proto) at this interval")
+	fs.IntVar(&opts.outputBuffer, "output-buffer", 0, "Buffer output in chunks of this many bytes, flushed after every refresh (0 disables)")
+	fs.StringVar(&opts.snapshotFile, "snapshot-file", "", "Atomically replace this file with the latest JSON snapshot on every refresh")
+	fs.BoolVar(&opts.noLoopback, "no-loopback", false, "Hide connections where both endpoints are loopback (127.0.0.0/8, ::1)")
+	fs.BoolVar(&opts.loopbackOnly, "loopback-only", false, "Only show connections where both endpoints are loopback")
+	fs.BoolVar(&opts.csv, "csv", false, "Deprecated: use -format csv")
+	fs.BoolVar(&opts.csvComment, "csv-comment", false, "With -csv, prepend a # comment line documenting column types")
 	fs.BoolVar(&opts.listen, "listen", true, "Include LISTEN sockets")
+	fs.StringVar(&opts.sortKey, "sort", "state", "Sort rows by: "+strings.Join(render.SortKeys, ", ")+" (age: oldest first, implies -age)")
+	fs.BoolVar(&opts.age, "age", false, "Add an AGE column: how long each connection has been seen by this tcpwatch")
+	fs.BoolVar(&opts.human, "human", false, "Render duration columns compactly, e.g. 1h2m or 350ms (table only)")
+	fs.BoolVar(&opts.unsignedOnly, "unsigned-only", false, "macOS: only show processes whose executable is unsigned or ad-hoc signed (via codesign)")
+	fs.BoolVar(&opts.mergeRepeats, "merge-repeats", false, "With -jsonl, replace runs of identical snapshots with one {\"repeat\":N,\"updated\":...} record")
+	fs.BoolVar(&opts.newListeners, "watch-new-listener", false, "Only report LISTEN sockets that appear while watching (a service opening a port)")
+	remotePorts := fs.String("watch-only-remote-port", "", "Only report connections to these remote `ports` as they appear, e.g. 25,4444 or 6660-6669")
+	fs.BoolVar(&opts.exitOnAlert, "exit-on-alert", false, "With -watch-new-listener or -watch-only-remote-port, exit with status 1 after the first alert")
+	fs.DurationVar(&opts.baseline, "baseline", 0, "With -watch-new-listener, treat listeners seen during this long after startup as known")
+	columns := fs.String("columns", "", "Comma-separated table columns: "+strings.Join(render.Columns, ", "))
+	warnPorts := fs.String("warn-port", "", "Mark connections whose local or remote port is one of these sensitive `ports` (e.g. 21,23,3389) in red and count them under the table")
+	fs.Float64Var(&opts.sample, "sample", 0, "Show only this `fraction` (0-1) of matching connections, picked by a hash of their identity so the same ones stay shown; counts, alerts and metrics still use every row")
+	identityFlag := fs.String("identity", "", "Comma-separated `fields` that make two rows the same connection for -events, -age, alerts and baseline diffs: "+strings.Join(identityFields, ", ")+" (default host,proto,local,remote,pid)")
+	preset := fs.String("preset", "", "Named column set: minimal, security or debug (-columns overrides it)")
+	fs.BoolVar(&opts.aggregate, "aggregate", false, "Read -jsonl snapshots from several hosts on stdin and show them combined with a HOST column")
+	fs.StringVar(&opts.hostLabel, "host-label", "", "Tag rows and snapshots with this host `name` and show a HOST column; \"auto\" uses the hostname")
+	fs.StringVar(&opts.resolveMethod, "proc-resolve-method", resolveGopsutil, "How to look up process names: gopsutil (ps as fallback), os (ps only) or os-first (gopsutil as fallback)")
+	fs.IntVar(&opts.procCacheSize, "proc-cache-size", 65536, "Maximum number of PIDs whose process details are cached")
+	fs.IntVar(&opts.width, "width", 0, "Truncate table columns to fit this total width (0 = no limit)")
+	fs.IntVar(&opts.flagRemoteOver, "flag-remote-over", 0, "Mark rows (\"!\" after REMOTE) whose remote IP has more than this many connections (0 disables)")
+	grep := fs.String("grep", "", "Only print table rows matching this `regexp`, applied to each row's line with cells separated by single spaces")
+	fs.BoolVar(&opts.grepKeepHeader, "grep-keep-header", false, "With -grep, also print the title, update time and header lines")
+	fs.StringVar(&opts.metricsAddr, "metrics-addr", "", "Serve Prometheus metrics (connection counts by state) on this address at /metrics, e.g. :9187")
+	fs.BoolVar(&opts.openMetrics, "openmetrics", false, "With -metrics-addr, serve the OpenMetrics text format instead of the Prometheus one")
+	fs.BoolVar(&opts.flushOnSignal, "flush-on-signal", false, "On SIGUSR2, write the latest snapshot to tcpwatch-<time>.json in the current directory (not on Windows)")
+	groupCIDR := fs.String("group-cidr", "", "Report connections per remote subnet instead of the table, e.g. /24 or /24,/48 (the IPv6 `prefix` defaults to /64)")
+	fs.StringVar(&opts.countUnique, "count-unique", "", "Report the number of distinct remote peers instead of the table: ip or addr (ip:port)")
+	fs.BoolVar(&opts.uniqueByProc, "by-process", false, "With -count-unique, also break the count down by process")
+	fs.BoolVar(&opts.noUnknown, "no-unknown", false, "Hide connections whose state the OS didn't report (UNKNOWN)")
+	fs.BoolVar(&opts.counters, "counters", false, "Linux: add BYTES and PKTS columns (sent/received totals per connection) from the kernel's tcp_info")
+	fs.BoolVar(&opts.sockDiag, "sock-diag", false, "Linux: add INODE and UID columns from netlink sock_diag")
+	fs.IntVar(&opts.fdWarnPercent, "per-process-limit", 0, "Show an FDS column (connections/soft fd limit) and mark processes using at least this `percent` of their limit (0 disables)")
+	fs.BoolVar(&opts.influx, "influx", false, "Deprecated: use -format influx")
+	fs.BoolVar(&opts.quiet, "quiet", false, "Don't print \"(no matching connections)\" under an empty table")
+	fs.BoolVar(&opts.includeThreads, "proc-include-threads", false, "Linux: when a PID is a thread ID, show the name of the process it belongs to (no-op elsewhere)")
+	fs.StringVar(&opts.baselineFile, "watch-compare-baseline-file", "", "Show only how connections differ from the snapshot saved in this `file` (by -snapshot-file, -flush-on-signal or -json), instead of the table")
+	fs.StringVar(&opts.proto, "proto", "tcp", "Socket kinds to list: tcp, unix (Unix domain sockets, path in LOCAL) or all")
+	fs.DurationVar(&opts.resolveBudget, "resolve-budget", 0, "Stop resolving uncached process names once a refresh has spent this long on them (e.g. 200ms); the rest show \"-\" until a later refresh (0 = no limit)")
+	fs.BoolVar(&opts.sectioned, "sectioned", false, "Print separate INBOUND (including listeners) and OUTBOUND tables, plus OTHER for rows without a direction (implies -direction all)")
+	fs.BoolVar(&opts.showCwd, "show-cwd", false, "Add a CWD column with each process's working directory (extra lookups per process, cached like names)")
+	fs.BoolVar(&opts.showListener, "show-listener", false, "Add a LISTENER column naming the listening socket (pid/process) that accepted each inbound connection")
+	fs.BoolVar(&opts.jsonCompact, "json-compact", false, "With -json, print each document on a single line instead of indented")
+	fs.StringVar(&opts.linePrefix, "line-prefix", "", "Prefix every table line with this `text`; %h is the host label (or hostname), %t the refresh time")
+	fs.IntVar(&opts.maxRemoteWidth, "max-remote-width", 0, "Shorten REMOTE cells longer than this many `characters` by cutting out the middle, keeping the port (0 = no limit)")
+	fs.StringVar(&opts.strict, "strict", "", "Validate -json/-jsonl rows: `mode` drop leaves out rows with garbage values, flag marks them (\"Invalid\" field); both warn on stderr")
+	fs.DurationVar(&opts.clearRate, "clear-rate", 0, "Clear the screen at most once per this `duration`; faster refreshes redraw in place to reduce flicker")
+	fs.BoolVar(&opts.headerOnce, "no-header-repeat", false, "With -no-clear, print the column header on the first refresh only")
+	fs.StringVar(&opts.separator, "sep", "", "Print the table unaligned and without color, cells joined by this `separator` (e.g. \"|\"); cells containing it or starting with a quote are quoted")
+	fs.DurationVar(&opts.enumTimeout, "enum-timeout", 0, "Give up on a refresh whose connection listing takes longer than this `duration` and try again next interval (0 = wait indefinitely)")
+	fs.BoolVar(&opts.tree, "tree", false, "Group the table by process: a line per process with its connections indented below (one line for a single connection)")
+	fs.BoolVar(&opts.pidTree, "pid-tree", false, "Like -tree, and nest child processes under their parent")
+	fs.IntVar(&opts.whoListens, "who-listens", 0, "Print \"PID process\" of whatever listens on this `port` and exit (status 1 if nothing does)")
+	fs.IntVar(&opts.growthAlert, "growth-alert", 0, "Alert on stderr when the ESTABLISHED count rose by at least `n` over -growth-window without ever dropping (a slow leak)")
+	fs.DurationVar(&opts.growthWindow, "growth-window", 5*time.Minute, "Sliding `window` for -growth-alert")
+	fs.BoolVar(&opts.lsofFallback, "lsof-fallback", false, "Ask lsof which process owns listening sockets reported with PID 0 (runs lsof on refreshes that have such listeners)")
+	fs.StringVar(&opts.loopback, "normalize-loopback", "", "Show every loopback address (127.0.0.0/8, ::1) as this `address`: 127.0.0.1 or ::1, so IPv4 and IPv6 loopback rows read alike")
+	fs.StringVar(&opts.ignoreFile, "watch-ignore-file", "", "Leave out connections matching the rules in this `file` (proc=NAME, port=N[-M], remote=IP[/BITS], one per line); reread on SIGHUP")
+	fs.BoolVar(&opts.seq, "seq", false, "Add a SEQ column numbering connections in the order this tcpwatch first saw them; a connection keeps its number while it lasts")
 	fs.BoolVar(&opts.header, "header", true, "Print table header")
+	fs.BoolVar(&opts.coalesce, "coalesce-listeners", false, "Collapse a process's 0.0.0.0 and [::] listeners on the same port into one \"*:port\" row (proto tcp46)")
+	fs.BoolVar(&opts.dedup, "dedup", false, "Collapse IPv6 link-local listeners that differ only by interface zone")
+	fs.BoolVar(&opts.collapse, "collapse-proc", false, "Show the process name only on the first of consecutive rows with the same PID")
+	fs.BoolVar(&opts.pidCount, "watch-pid-count", false, "Print \"timestamp pid count\" lines per refresh for graphing connection growth (combine with -pid)")
+	fs.BoolVar(&opts.pidCount, "pid-count", false, "Short for -watch-pid-count")
+	fs.BoolVar(&opts.events, "events", false, "Print connection changes between refreshes (+ added, - removed, ~ state changed) instead of the table")
+	fs.BoolVar(&opts.onlyStateChanges, "only-state-changes", false, "With -events, only print state changes of existing connections")
+	fs.BoolVar(&opts.syslog, "syslog", false, "With -events, also send each event to syslog as an RFC 5424 message")
+	fs.StringVar(&opts.webhook, "webhook", "", "With -events, also POST each refresh's events as JSON to this `url`; with -growth-alert, also its alerts")
+	fs.StringVar(&opts.syslogAddr, "syslog-addr", "", "Remote syslog server for -syslog (host:port, udp://host:port or tcp://host:port; default: local syslog)")
+	fs.BoolVar(&opts.reusePort, "reuseport", false, "With -ports, show one line per listening address with every process sharing it (SO_REUSEPORT workers, inherited sockets)")
+	fs.BoolVar(&opts.ports, "ports", false, "Show an open ports report (listening sockets with their process, sorted by port)")
+	fs.StringVar(&opts.highlight, "highlight", "", "Bold rows containing this substring in any field and dim the rest (requires a color terminal)")
+	fs.StringVar(&opts.direction, "direction", "", "Classify connections by direction and show a DIR column: in, out or all")
+	fs.BoolVar(&opts.stateCodes, "state-codes", false, "Show the OS's numeric TCP state next to its name, e.g. ESTABLISHED(1)")
+	fs.BoolVar(&opts.serviceNames, "service-names", false, "Show a SERVICE column naming the well-known service (local port for listeners/inbound, remote port for outbound)")
+	fs.StringVar(&opts.backend, "backend", backendGopsutil, "Connection source: gopsutil, ss (Linux) or netstat")
+	fs.BoolVar(&opts.restartOnError, "restart-on-error", false, "After repeated refresh failures, reset process and connection caches and retry from scratch")
+	fs.BoolVar(&opts.quietErrors, "quiet-errors", false, "In watch mode, log a refresh error only when it differs from the previous one")
+	fs.BoolVar(&opts.verbose, "verbose", false, "Log diagnostics such as the time each refresh takes to stderr")
+	fs.BoolVar(&opts.printSchema, "print-schema", false, "Print a JSON Schema for the -jsonl snapshot format and exit")
+	fs.BoolVar(&opts.diag, "diag", false, "Print platform diagnostics for bug reports as JSON and exit")
+	fs.StringVar(&opts.replayFile, "replay", "", "Play back connections recorded in this JSON `file` (an array of -json row arrays), one snapshot per refresh")
+	fs.StringVar(&opts.recordFile, "record", "", "Also save every refresh's rows (after filters) to this `file` for -replay")
+	fs.BoolVar(&opts.replayLoop, "replay-loop", false, "With -replay, start over after the last snapshot instead of exiting")
+	fs.StringVar(&opts.sshTarget, "ssh", "", "Watch a remote host's connections by running ss/netstat over ssh (e.g. user@host)")
+	fs.BoolVar(&opts.strictPlat, "strict-platform", false, "Exit with an error instead of running with reduced functionality on unsupported platforms")
+
+	opts.layout = render.DefaultLayout
+	fs.IntVar(&opts.layout.MinWidth, "min-width", render.DefaultLayout.MinWidth, "Minimum table column width")
+	fs.IntVar(&opts.layout.Padding, "pad", render.DefaultLayout.Padding, "Spaces between table columns")
+	padChar := fs.String("pad-char", string(render.DefaultLayout.PadChar), "Character used to pad table columns (a single ASCII character)")
+
+	fs.DurationVar(&opts.stuck, "stuck", 0, "Only show connections that have stayed in a -stuck-state state for at least this `duration` (e.g. 5m), with an IN-STATE column")
+	stuckStates := fs.String("stuck-state", "CLOSE_WAIT", "Comma-separated states -stuck watches")
+	resolveDNS := fs.Bool("resolve", false, "Show remote addresses by their reverse DNS (PTR) name; JSON keeps the IP in Remote and adds RemoteHost")
+	fs.BoolVar(&opts.resolveBoth, "resolve-both", false, "Like -resolve, but show \"name:port (ip)\" so the address stays visible")
+	redact := fs.Bool("redact", false, "Mask IP addresses (private ranges keep their first octet, public ones are hashed) for sharing output")
 
 	states := fs.String("state", "", "Comma-separated TCP states to include (e.g. ESTABLISHED,CLOSE_WAIT)")
 	pid := fs.String("pid", "", "Only show connections owned by this PID")
 	port := fs.Int("port", 0, "Only show connections where local or remote port matches this value")
-	proc := fs.String("proc", "", "Only show connections whose process name contains this substring (case-insensitive)")
+	proc := fs.String("proc", "", "Only show connections whose process name contains this substring, or any of several comma-separated ones (case-insensitive)")
+	fs.StringVar(&opts.matchField, "match-field", "name", "What -proc matches against: name, exe (executable path) or cmdline")
 
 	fs.Usage = func() {
 		fmt.Fprintln(fs.Output(), "tcpwatch: live TCP connection viewer")
 		fmt.Fprintln(fs.Output(), "")
 		fmt.Fprintln(fs.Output(), "Uses system APIs via gopsutil for cross-platform TCP monitoring.")
+		fmt.Fprintf(fs.Output(), "Platform: %s\n", platformName())
 		fmt.Fprintln(fs.Output(), "")
 		fmt.Fprintln(fs.Output(), "Usage:")
 		fmt.Fprintln(fs.Output(), "  tcpwatch [flags]")
 		fmt.Fprintln(fs.Output(), "")
 		fmt.Fprintln(fs.Output(), "Flags:")
-		fs.PrintDefaults()
+		printVisibleDefaults(fs)
 	}
 
 	if err := fs.Parse(args); err != nil {
 		return options{}, err
 	}
 
-	if opts.jsonOut && opts.jsonLines {
-		return options{}, fmt.Errorf("-json and -jsonl are mutually exclusive")
+	if err := resolveFormat(&opts, *format); err != nil {
+		return options{}, err
+	}
+	if opts.jsonCompact && !opts.jsonOut {
+		return options{}, fmt.Errorf("-json-compact requires -json (-jsonl is always compact)")
+	}
+	if opts.csv && (opts.jsonOut || opts.jsonLines || opts.ports || opts.pidCount || opts.events) {
+		return options{}, fmt.Errorf("-csv can't be combined with -json, -jsonl, -ports, -watch-pid-count or -events")
+	}
+	if opts.csvComment && !opts.csv {
+		return options{}, fmt.Errorf("-csv-comment requires -csv")
+	}
+
+	if opts.wait < 0 {
+		return options{}, fmt.Errorf("-wait must be >= 0")
+	}
+	if opts.wait > 0 && !opts.once {
+		return options{}, fmt.Errorf("-wait requires -once")
+	}
+
+	if opts.duration < 0 {
+		return options{}, fmt.Errorf("-duration must be >= 0")
+	}
+
+	if opts.stableIntervals < 1 {
+		return options{}, fmt.Errorf("-stable-intervals must be at least 1")
+	}
+	if opts.stableTimeout <= 0 {
+		return options{}, fmt.Errorf("-stable-timeout must be > 0")
+	}
+	if opts.untilStable && (opts.wait > 0 || opts.step || opts.events || opts.aggregate) {
+		return options{}, fmt.Errorf("-watch-until-stable can't be combined with -wait, -refresh-on-key, -events or -aggregate")
+	}
+
+	if opts.summaryEvery < 0 {
+		return options{}, fmt.Errorf("-summary-every must be >= 0")
+	}
+	if opts.mergeRepeats && (!opts.jsonLines || opts.ports || opts.pidCount || opts.events) {
+		return options{}, fmt.Errorf("-merge-repeats requires -jsonl connection snapshots")
+	}
+
+	if opts.summaryEvery > 0 && !opts.jsonLines {
+		return options{}, fmt.Errorf("-summary-every requires the -jsonl snapshot stream")
+	}
+
+	if opts.noLoopback && opts.loopbackOnly {
+		return options{}, fmt.Errorf("-no-loopback and -loopback-only are mutually exclusive")
+	}
+
+	if opts.events {
+		if opts.once {
+			return options{}, fmt.Errorf("-events needs watch mode; it can't be combined with -once")
+		}
+		if opts.jsonOut || opts.ports || opts.pidCount {
+			return options{}, fmt.Errorf("-events can't be combined with -json, -ports or -watch-pid-count (use -jsonl for JSON events)")
+		}
+	}
+	if opts.newListeners {
+		if opts.once {
+			return options{}, fmt.Errorf("-watch-new-listener needs watch mode; it can't be combined with -once")
+		}
+		if !opts.listen {
+			return options{}, fmt.Errorf("-watch-new-listener can't be combined with -listen=false")
+		}
+		if opts.events || opts.jsonOut || opts.csv || opts.ports || opts.pidCount {
+			return options{}, fmt.Errorf("-watch-new-listener can't be combined with -events, -json, -csv, -ports or -watch-pid-count (use -jsonl for JSON alerts)")
+		}
+	}
+	if *remotePorts != "" {
+		rs, err := parsePortRanges(*remotePorts)
+		if err != nil {
+			return options{}, fmt.Errorf("-watch-only-remote-port: %w", err)
+		}
+		opts.remotePorts = rs
+		if opts.newListeners {
+			return options{}, fmt.Errorf("-watch-only-remote-port and -watch-new-listener are mutually exclusive")
+		}
+		if opts.events || opts.jsonOut || opts.csv || opts.ports || opts.pidCount {
+			return options{}, fmt.Errorf("-watch-only-remote-port can't be combined with -events, -json, -csv, -ports or -watch-pid-count (use -jsonl for JSON alerts)")
+		}
+	}
+	if opts.exitOnAlert && !opts.newListeners && opts.remotePorts == nil {
+		return options{}, fmt.Errorf("-exit-on-alert requires -watch-new-listener or -watch-only-remote-port")
+	}
+	if opts.baseline != 0 && !opts.newListeners {
+		return options{}, fmt.Errorf("-baseline requires -watch-new-listener")
+	}
+	if opts.baseline < 0 {
+		return options{}, fmt.Errorf("-baseline must be >= 0")
+	}
+	if opts.syslogAddr != "" {
+		opts.syslog = true
+	}
+	if opts.webhook != "" && !opts.events && opts.growthAlert == 0 {
+		return options{}, fmt.Errorf("-webhook requires -events or -growth-alert")
+	}
+	if opts.syslog && !opts.events {
+		return options{}, fmt.Errorf("-syslog requires -events")
+	}
+	if opts.onlyStateChanges && !opts.events {
+		return options{}, fmt.Errorf("-only-state-changes requires -events")
+	}
+
+	switch opts.countUnique {
+	case "", "ip", "addr":
+	default:
+		return options{}, fmt.Errorf("invalid -count-unique %q (want ip or addr)", opts.countUnique)
+	}
+	if opts.countUnique != "" && (opts.ports || opts.pidCount || opts.events || opts.csv) {
+		return options{}, fmt.Errorf("-count-unique can't be combined with -ports, -watch-pid-count, -events or -csv")
+	}
+	if *groupCIDR != "" {
+		bits, err := parseCIDRBits(*groupCIDR)
+		if err != nil {
+			return options{}, err
+		}
+		if opts.countUnique != "" || opts.ports || opts.pidCount || opts.events || opts.csv || opts.influx {
+			return options{}, fmt.Errorf("-group-cidr can't be combined with -count-unique, -ports, -watch-pid-count, -events, -csv or -influx")
+		}
+		opts.groupCIDR = bits
+	}
+	if opts.uniqueByProc && opts.countUnique == "" {
+		return options{}, fmt.Errorf("-by-process requires -count-unique")
+	}
+
+	if (opts.influx || opts.format == formatPrometheus) && (opts.ports || opts.pidCount || opts.events || opts.newListeners || opts.remotePorts != nil || opts.countUnique != "" || opts.groupCIDR != nil) {
+		return options{}, fmt.Errorf("-format %s can't be combined with -ports, -watch-pid-count, -count-unique, -group-cidr or alert modes", opts.format)
+	}
+
+	if opts.baselineFile != "" && (opts.events || opts.newListeners || opts.remotePorts != nil || opts.jsonOut || opts.csv || opts.ports || opts.pidCount || opts.countUnique != "" || opts.influx || opts.format == formatPrometheus || opts.aggregate) {
+		return options{}, fmt.Errorf("-watch-compare-baseline-file can't be combined with -events, alert modes, -json, -csv, -ports, -watch-pid-count, -count-unique, -influx, -format prometheus or -aggregate (use -jsonl for JSON events)")
+	}
+
+	if opts.ports && opts.pidCount {
+		return options{}, fmt.Errorf("-ports and -watch-pid-count are mutually exclusive")
+	}
+	if opts.separator != "" && (strings.ContainsAny(opts.separator, "\n\"") || opts.csv || opts.jsonOut || opts.jsonLines) {
+		return options{}, fmt.Errorf("-sep must not contain newlines or quotes, and only applies to the table (not -csv, -json or -jsonl)")
+	}
+	if opts.separator != "" && (opts.width > 0 || strings.TrimSpace(opts.highlight) != "") {
+		return options{}, fmt.Errorf("-sep prints unaligned, uncolored cells; it can't be combined with -width or -highlight")
+	}
+	if opts.whoListens < 0 || opts.whoListens > 65535 {
+		return options{}, fmt.Errorf("-who-listens must be a port between 1 and 65535")
+	}
+	if opts.whoListens > 0 && opts.aggregate {
+		return options{}, fmt.Errorf("-who-listens can't be combined with -aggregate")
+	}
+	if opts.pidTree {
+		opts.tree = true
+	}
+	if opts.tree && (opts.sectioned || opts.separator != "" || len(opts.columns) > 0) {
+		return options{}, fmt.Errorf("-tree and -pid-tree can't be combined with -sectioned, -sep, -columns or -preset")
+	}
+	if opts.headerOnce && !opts.noClear {
+		return options{}, fmt.Errorf("-no-header-repeat requires -no-clear")
+	}
+	if opts.enumTimeout < 0 {
+		return options{}, fmt.Errorf("-enum-timeout must not be negative")
+	}
+	if opts.clearRate < 0 {
+		return options{}, fmt.Errorf("-clear-rate must not be negative")
+	}
+	if opts.reusePort && !opts.ports {
+		return options{}, fmt.Errorf("-reuseport requires -ports")
+	}
+
+	if *warnPorts != "" {
+		rs, err := parsePortRanges(*warnPorts)
+		if err != nil {
+			return options{}, fmt.Errorf("-warn-port: %w", err)
+		}
+		opts.warnPorts = rs
+	}
+
+	if opts.sample < 0 || opts.sample > 1 {
+		return options{}, fmt.Errorf("-sample must be between 0 and 1")
+	}
+
+	if opts.growthAlert < 0 {
+		return options{}, fmt.Errorf("-growth-alert must not be negative")
+	}
+	if opts.growthAlert > 0 && opts.growthWindow < opts.interval {
+		return options{}, fmt.Errorf("-growth-window must be at least -interval")
+	}
+	if opts.growthAlert > 0 && (opts.once || opts.aggregate) {
+		return options{}, fmt.Errorf("-growth-alert can't be combined with -once or -aggregate")
+	}
+
+	if *identityFlag != "" {
+		id, err := parseIdentity(*identityFlag)
+		if err != nil {
+			return options{}, fmt.Errorf("-identity: %w", err)
+		}
+		opts.identity = id
+	}
+
+	if *preset != "" {
+		cols, ok := render.Presets[*preset]
+		if !ok {
+			return options{}, fmt.Errorf("invalid -preset %q (want minimal, security or debug)", *preset)
+		}
+		opts.columns = cols
+	}
+	if *columns != "" {
+		cols, err := render.ParseColumns(*columns)
+		if err != nil {
+			return options{}, fmt.Errorf("-columns: %w", err)
+		}
+		opts.columns = cols
+	}
+	if opts.aggregate {
+		if opts.sshTarget != "" || opts.wait > 0 || opts.snapshotFile != "" {
+			return options{}, fmt.Errorf("-aggregate reads snapshots from stdin; it can't be combined with -ssh, -wait or -snapshot-file")
+		}
+	}
+	if opts.hostLabel == "auto" {
+		name, err := os.Hostname()
+		if err != nil {
+			return options{}, fmt.Errorf("-host-label auto: %w", err)
+		}
+		opts.hostLabel = name
+	}
+	if opts.linePrefix != "" && (opts.jsonOut || opts.jsonLines || opts.csv) {
+		return options{}, fmt.Errorf("-line-prefix only applies to text output; it can't be combined with -json, -jsonl or -csv")
+	}
+	if opts.sectioned {
+		if opts.jsonOut || opts.jsonLines || opts.csv {
+			return options{}, fmt.Errorf("-sectioned only applies to the table; it can't be combined with -json, -jsonl or -csv")
+		}
+		if opts.direction == "" {
+			opts.direction = "all"
+		}
+	}
+	// Columns that need extra work switch on the option that provides it.
+	if hasColumn(opts, "dir") && opts.direction == "" {
+		opts.direction = "all"
+	}
+	if hasColumn(opts, "service") {
+		opts.serviceNames = true
+	}
+	if hasColumn(opts, "age") || opts.sortKey == "age" {
+		opts.age = true
+	}
+	if hasColumn(opts, "listener") {
+		opts.showListener = true
+	}
+	if hasColumn(opts, "cwd") {
+		opts.showCwd = true
+	}
+	if hasColumn(opts, "seq") {
+		opts.seq = true
+	}
+	if hasColumn(opts, "fds") && opts.fdWarnPercent == 0 {
+		opts.fdWarnPercent = 80
+	}
+	if hasColumn(opts, "inode") || hasColumn(opts, "uid") {
+		opts.sockDiag = true
+	}
+	if hasColumn(opts, "bytes") || hasColumn(opts, "pkts") {
+		opts.counters = true
+	}
+	if opts.sockDiag || opts.counters {
+		if !sockDiagSupported {
+			return options{}, fmt.Errorf("-sock-diag and -counters (and the inode, uid, bytes and pkts columns) are only available on Linux")
+		}
+		if opts.sshTarget != "" || opts.aggregate {
+			return options{}, fmt.Errorf("-sock-diag and -counters can't be combined with -ssh or -aggregate")
+		}
+	}
+
+	if !slices.Contains(render.SortKeys, opts.sortKey) {
+		return options{}, fmt.Errorf("invalid -sort %q (want %s)", opts.sortKey, strings.Join(render.SortKeys, ", "))
+	}
+
+	switch opts.matchField {
+	case "name", "exe", "cmdline":
+	default:
+		return options{}, fmt.Errorf("invalid -match-field %q (want name, exe or cmdline)", opts.matchField)
+	}
+
+	if opts.replayFile != "" && (opts.sshTarget != "" || opts.backend != backendGopsutil || opts.aggregate || opts.proto != "tcp") {
+		return options{}, fmt.Errorf("-replay can't be combined with -ssh, -backend, -aggregate or -proto")
+	}
+	switch opts.strict {
+	case "", "drop", "flag":
+	default:
+		return options{}, fmt.Errorf("-strict must be drop or flag")
+	}
+	if opts.strict != "" && !opts.jsonOut && !opts.jsonLines {
+		return options{}, fmt.Errorf("-strict requires -json or -jsonl")
+	}
+	if opts.recordFile != "" && opts.aggregate {
+		return options{}, fmt.Errorf("-record can't be combined with -aggregate")
+	}
+	if opts.replayLoop && opts.replayFile == "" {
+		return options{}, fmt.Errorf("-replay-loop requires -replay")
+	}
+	if opts.replayFile != "" && (opts.matchField != "name" || opts.unsignedOnly || opts.sockDiag || opts.counters) {
+		return options{}, fmt.Errorf("-replay only has recorded process names; it can't be combined with -match-field, -unsigned-only, -sock-diag or -counters")
+	}
+
+	if strings.HasPrefix(opts.sshTarget, "-") {
+		return options{}, fmt.Errorf("invalid -ssh %q: the target can't start with \"-\"", opts.sshTarget)
+	}
+	if opts.sshTarget != "" && opts.matchField != "name" {
+		return options{}, fmt.Errorf("-match-field %s isn't available with -ssh", opts.matchField)
+	}
+	if opts.sshTarget != "" && opts.unsignedOnly {
+		return options{}, fmt.Errorf("-unsigned-only isn't available with -ssh")
+	}
+	if opts.sshTarget != "" && opts.stateCodes {
+		return options{}, fmt.Errorf("-state-codes isn't available with -ssh")
+	}
+
+	switch opts.direction {
+	case "", "in", "out", "all":
+	default:
+		return options{}, fmt.Errorf("invalid -direction %q (want in, out or all)", opts.direction)
+	}
+
+	if *grep != "" {
+		re, err := regexp.Compile(*grep)
+		if err != nil {
+			return options{}, fmt.Errorf("invalid -grep: %w", err)
+		}
+		opts.grep = re
+	}
+	if opts.grep != nil && (opts.jsonOut || opts.jsonLines || opts.csv || opts.events || opts.ports || opts.pidCount || opts.countUnique != "" || opts.newListeners || opts.remotePorts != nil) {
+		return options{}, fmt.Errorf("-grep only filters the connection table")
+	}
+	if opts.grepKeepHeader && opts.grep == nil {
+		return options{}, fmt.Errorf("-grep-keep-header requires -grep")
+	}
+
+	if opts.metricsAddr != "" && (opts.once || opts.aggregate) {
+		return options{}, fmt.Errorf("-metrics-addr needs watch mode; it can't be combined with -once or -aggregate")
+	}
+	if opts.openMetrics && opts.metricsAddr == "" {
+		return options{}, fmt.Errorf("-openmetrics requires -metrics-addr")
+	}
+
+	if opts.fdWarnPercent < 0 || opts.fdWarnPercent > 100 {
+		return options{}, fmt.Errorf("-per-process-limit must be between 0 and 100")
+	}
+
+	if opts.maxRemoteWidth < 0 {
+		return options{}, fmt.Errorf("-max-remote-width must be >= 0")
+	}
+
+	if opts.flagRemoteOver < 0 {
+		return options{}, fmt.Errorf("-flag-remote-over must be >= 0")
+	}
+
+	if opts.width < 0 {
+		return options{}, fmt.Errorf("-width must be >= 0")
+	}
+
+	switch opts.resolveMethod {
+	case resolveGopsutil, resolveOS, resolveOSFirst:
+	default:
+		return options{}, fmt.Errorf("invalid -proc-resolve-method %q (want gopsutil, os or os-first)", opts.resolveMethod)
+	}
+
+	if opts.resolveBudget < 0 {
+		return options{}, fmt.Errorf("-resolve-budget must be >= 0")
+	}
+	if opts.procCacheSize < 1 {
+		return options{}, fmt.Errorf("-proc-cache-size must be at least 1")
+	}
+
+	if opts.outputBuffer < 0 {
+		return options{}, fmt.Errorf("-output-buffer must be >= 0")
+	}
+
+	if opts.layout.MinWidth < 0 || opts.layout.Padding < 0 {
+		return options{}, fmt.Errorf("-min-width and -pad must be >= 0")
+	}
+	if len(*padChar) != 1 {
+		return options{}, fmt.Errorf("-pad-char must be a single ASCII character")
+	}
+	opts.layout.PadChar = (*padChar)[0]
+
+	switch opts.proto {
+	case "tcp":
+	case "unix", "all":
+		if runtime.GOOS == "windows" {
+			return options{}, fmt.Errorf("-proto %s isn't available on Windows", opts.proto)
+		}
+		if opts.backend != backendGopsutil || opts.sshTarget != "" {
+			return options{}, fmt.Errorf("-proto %s requires the gopsutil backend and can't be combined with -ssh", opts.proto)
+		}
+	default:
+		return options{}, fmt.Errorf("invalid -proto %q (want tcp, unix or all)", opts.proto)
+	}
+
+	switch opts.loopback {
+	case "", "127.0.0.1", "::1":
+	default:
+		// Addresses must stay ip:port: -dedup, -coalesce-listeners and
+		// -group-cidr parse them.
+		return options{}, fmt.Errorf("invalid -normalize-loopback %q (want 127.0.0.1 or ::1)", opts.loopback)
+	}
+
+	if opts.lsofFallback {
+		if runtime.GOOS == "windows" {
+			return options{}, fmt.Errorf("-lsof-fallback isn't available on Windows")
+		}
+		if !localPIDs(opts) {
+			return options{}, fmt.Errorf("-lsof-fallback can't be combined with -ssh or -replay")
+		}
+	}
+
+	if err := validateBackend(opts.backend); err != nil {
+		return options{}, err
 	}
 
 	if opts.interval <= 0 {
@@ -318,10 +1892,89 @@ func parseFlags(args []string) (options, error) {
 	}
 
 	opts.stateAllow = parseStateAllow(*states)
-	opts.procFilter = strings.TrimSpace(*proc)
+	if _, listen := opts.stateAllow["LISTEN"]; opts.whoListens > 0 && len(opts.stateAllow) > 0 && (!listen || len(opts.stateAllow) > 1) {
+		return options{}, fmt.Errorf("-who-listens only looks at listeners; -state can't select anything else")
+	}
+	for _, p := range strings.Split(*proc, ",") {
+		if p = strings.TrimSpace(p); p != "" {
+			opts.procFilters = append(opts.procFilters, p)
+		}
+	}
+	if opts.stuck < 0 {
+		return options{}, fmt.Errorf("-stuck must be >= 0")
+	}
+	opts.stuckStates = parseStateAllow(*stuckStates)
+	if opts.stuck > 0 && len(opts.stuckStates) == 0 {
+		return options{}, fmt.Errorf("-stuck-state must name at least one state")
+	}
+
+	if *resolveDNS || opts.resolveBoth {
+		if *redact {
+			return options{}, fmt.Errorf("-resolve and -resolve-both can't be combined with -redact")
+		}
+		opts.dns = newDNSCache()
+	}
+	if *redact {
+		opts.redact = newRedactor()
+	}
+	opts.highlight = strings.TrimSpace(opts.highlight)
+	opts.color = colorEnabled()
+	if opts.highlight != "" && !opts.color && !opts.jsonOut && !opts.jsonLines {
+		fmt.Fprintln(os.Stderr, "tcpwatch: -highlight requires a color terminal; ignoring")
+	}
 	return opts, nil
 }
 
+// colorEnabled reports whether stdout is a terminal that should get ANSI
+// colors. NO_COLOR (https://no-color.org) disables them.
+func colorEnabled() bool {
+	if os.Getenv("NO_COLOR") != "" {
+		return false
+	}
+	fi, err := os.Stdout.Stat()
+	return err == nil && fi.Mode()&os.ModeCharDevice != 0
+}
+
+// secondsDuration is a flag.Value like the standard duration flag, except
+// that a bare number such as "2" or "1.5" is taken as seconds.
+type secondsDuration time.Duration
+
+func (d *secondsDuration) String() string {
+	return time.Duration(*d).String()
+}
+
+func (d *secondsDuration) Set(s string) error {
+	s = strings.TrimSpace(s)
+	if _, err := strconv.ParseFloat(s, 64); err == nil {
+		s += "s"
+	}
+	v, err := time.ParseDuration(s)
+	if err != nil {
+		return err
+	}
+	*d = secondsDuration(v)
+	return nil
+}
+
+// hiddenFlags are accepted but left out of -h output.
+var hiddenFlags = map[string]bool{
+	"print-schema": true,
+	"diag":         true,
+}
+
+func printVisibleDefaults(fs *flag.FlagSet) {
+	visible := flag.NewFlagSet(fs.Name(), flag.ContinueOnError)
+	visible.SetOutput(fs.Output())
+	fs.VisitAll(func(f *flag.Flag) {
+		if hiddenFlags[f.Name] {
+			return
+		}
+		visible.Var(f.Value, f.Name, f.Usage)
+		visible.Lookup(f.Name).DefValue = f.DefValue
+	})
+	visible.PrintDefaults()
+}
+
 func parseStateAllow(csv string) map[string]struct{} {
 	csv = strings.TrimSpace(csv)
 	if csv == "" {
@@ -0,0 +1,164 @@
+package main
+
+import (
+	"net/netip"
+	"syscall"
+	"testing"
+	"time"
+
+	gnet "github.com/shirou/gopsutil/v4/net"
+)
+
+func TestSecondsDurationSet(t *testing.T) {
+	tests := []struct {
+		arg     string
+		want    time.Duration
+		wantErr bool
+	}{
+		{arg: "2", want: 2 * time.Second},
+		{arg: "1.5", want: 1500 * time.Millisecond},
+		{arg: "500ms", want: 500 * time.Millisecond},
+		{arg: "2s", want: 2 * time.Second},
+		{arg: " 3 ", want: 3 * time.Second},
+		{arg: "1m", want: time.Minute},
+		{arg: "fast", wantErr: true},
+		{arg: "2 s", wantErr: true},
+		{arg: "", wantErr: true},
+	}
+	for _, tt := range tests {
+		var d secondsDuration
+		err := d.Set(tt.arg)
+		if (err != nil) != tt.wantErr {
+			t.Errorf("Set(%q) error = %v, want error %v", tt.arg, err, tt.wantErr)
+			continue
+		}
+		if !tt.wantErr && time.Duration(d) != tt.want {
+			t.Errorf("Set(%q) = %s, want %s", tt.arg, time.Duration(d), tt.want)
+		}
+	}
+}
+
+// Zero and negative values parse as durations but aren't usable intervals.
+func TestIntervalRejectsNonPositive(t *testing.T) {
+	for _, arg := range []string{"0", "0s", "-1", "-0.5", "-2s"} {
+		if opts, err := parseFlags([]string{"-interval", arg}); err == nil {
+			t.Errorf("-interval %q: accepted as %s, want an error", arg, opts.interval)
+		}
+	}
+}
+
+func TestIntervalShortAlias(t *testing.T) {
+	opts, err := parseFlags([]string{"-I", "2"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if opts.interval != 2*time.Second {
+		t.Errorf("-I 2: interval = %s, want 2s", opts.interval)
+	}
+}
+
+func TestIsListener(t *testing.T) {
+	tests := []struct {
+		name  string
+		c     gnet.ConnectionStat
+		state string
+		want  bool
+	}{
+		{"LISTEN", tcpConn(syscall.AF_INET, gnet.Addr{IP: "0.0.0.0", Port: 80}, gnet.Addr{IP: "0.0.0.0"}, "LISTEN", 1), "LISTEN", true},
+		{"NONE with *:0", tcpConn(syscall.AF_INET, gnet.Addr{IP: "0.0.0.0", Port: 80}, gnet.Addr{IP: "*"}, "NONE", 1), "NONE", true},
+		{"NONE with [::]:0", tcpConn(syscall.AF_INET6, gnet.Addr{IP: "::", Port: 80}, gnet.Addr{IP: "::"}, "NONE", 1), "NONE", true},
+		{"UNKNOWN without a remote", tcpConn(syscall.AF_INET, gnet.Addr{IP: "127.0.0.1", Port: 80}, gnet.Addr{}, "", 1), "UNKNOWN", true},
+		{"NONE with a remote", tcpConn(syscall.AF_INET, gnet.Addr{IP: "10.0.0.5", Port: 80}, gnet.Addr{IP: "10.0.0.9", Port: 40000}, "NONE", 1), "NONE", false},
+		{"NONE with a remote IP only", tcpConn(syscall.AF_INET, gnet.Addr{IP: "10.0.0.5", Port: 80}, gnet.Addr{IP: "10.0.0.9"}, "NONE", 1), "NONE", false},
+		{"ESTABLISHED", tcpConn(syscall.AF_INET, gnet.Addr{IP: "10.0.0.5", Port: 80}, gnet.Addr{IP: "10.0.0.9", Port: 40000}, "ESTABLISHED", 1), "ESTABLISHED", false},
+		{"unix", gnet.ConnectionStat{Family: syscall.AF_UNIX, Laddr: gnet.Addr{IP: "/run/docker.sock"}}, "-", false},
+	}
+	for _, tt := range tests {
+		if got := isListener(tt.c, tt.state); got != tt.want {
+			t.Errorf("%s: isListener = %v, want %v", tt.name, got, tt.want)
+		}
+	}
+}
+
+func TestFormatAddr(t *testing.T) {
+	tests := []struct {
+		a        gnet.Addr
+		loopback string
+		want     string
+	}{
+		{gnet.Addr{}, "", "*:*"},
+		{gnet.Addr{Port: 80}, "", "*:80"},
+		{gnet.Addr{IP: "10.0.0.5", Port: 80}, "", "10.0.0.5:80"},
+		{gnet.Addr{IP: "2001:db8::1", Port: 443}, "", "[2001:db8::1]:443"},
+		{gnet.Addr{IP: "fe80::1%en0", Port: 22}, "", "[fe80::1%en0]:22"},
+		{gnet.Addr{IP: "::ffff:10.0.0.5", Port: 80}, "", "::ffff:10.0.0.5:80"},
+		{gnet.Addr{IP: "127.0.0.1", Port: 80}, "", "127.0.0.1:80"},
+		{gnet.Addr{IP: "::1", Port: 80}, "", "[::1]:80"},
+		// -normalize-loopback
+		{gnet.Addr{IP: "127.0.0.2", Port: 80}, "127.0.0.1", "127.0.0.1:80"},
+		{gnet.Addr{IP: "::1", Port: 80}, "127.0.0.1", "127.0.0.1:80"},
+		{gnet.Addr{IP: "127.0.0.1", Port: 80}, "::1", "[::1]:80"},
+		{gnet.Addr{IP: "::1", Port: 80}, "::1", "[::1]:80"},
+		{gnet.Addr{IP: "10.0.0.5", Port: 80}, "::1", "10.0.0.5:80"},
+	}
+	for _, tt := range tests {
+		got := formatAddr(tt.a, tt.loopback)
+		if got != tt.want {
+			t.Errorf("formatAddr(%+v, %q) = %q, want %q", tt.a, tt.loopback, got, tt.want)
+		}
+		if tt.loopback != "" {
+			if _, err := netip.ParseAddrPort(got); err != nil {
+				t.Errorf("formatAddr(%+v, %q) = %q does not parse: %v", tt.a, tt.loopback, got, err)
+			}
+		}
+	}
+}
+
+func TestNormalizeLoopbackForms(t *testing.T) {
+	for _, form := range []string{"127.0.0.1", "::1"} {
+		if _, err := parseFlags([]string{"-normalize-loopback", form}); err != nil {
+			t.Errorf("-normalize-loopback %s: %v", form, err)
+		}
+	}
+	if _, err := parseFlags([]string{"-normalize-loopback", "localhost"}); err == nil {
+		t.Error("-normalize-loopback localhost accepted; dedup can't parse the addresses it gives")
+	}
+}
+
+func TestSSHTargetOption(t *testing.T) {
+	if _, err := parseFlags([]string{"-ssh", "user@host"}); err != nil {
+		t.Errorf("-ssh user@host: %v", err)
+	}
+	if _, err := parseFlags([]string{"-ssh", "-oProxyCommand=touch /tmp/x"}); err == nil {
+		t.Error("-ssh accepted a target that ssh would read as an option")
+	}
+	if _, err := parseFlags([]string{"-ssh", "user@host", "-state-codes"}); err == nil {
+		t.Error("-ssh accepted -state-codes")
+	}
+}
+
+func TestSepRejectsAlignmentAndColor(t *testing.T) {
+	for _, args := range [][]string{{"-sep", "|", "-width", "80"}, {"-sep", "|", "-highlight", "nginx"}} {
+		if _, err := parseFlags(args); err == nil {
+			t.Errorf("%q accepted", args)
+		}
+	}
+}
+
+func TestWatchPIDCountFlag(t *testing.T) {
+	for _, name := range []string{"-watch-pid-count", "-pid-count"} {
+		opts, err := parseFlags([]string{name, "-pid", "1"})
+		if err != nil || !opts.pidCount {
+			t.Errorf("%s: pidCount = %v, err = %v", name, opts.pidCount, err)
+		}
+	}
+}
+
+func TestRefreshOnKeyFlag(t *testing.T) {
+	for _, name := range []string{"-refresh-on-key", "-step"} {
+		opts, err := parseFlags([]string{name})
+		if err != nil || !opts.step {
+			t.Errorf("%s: step = %v, err = %v", name, opts.step, err)
+		}
+	}
+}
@@ -0,0 +1,190 @@
+package main
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/bulent/morzer/tools/tcpwatch/internal/render"
+)
+
+// metricFamily is one metric with its samples, independent of the exposition
+// format.
+type metricFamily struct {
+	name    string
+	help    string
+	typ     string // "gauge"
+	unit    string // OpenMetrics unit; name ends in "_"+unit when set
+	samples []metricSample
+}
+
+type metricSample struct {
+	labels [][2]string
+	value  float64
+}
+
+// connGroup identifies a set of connections counted together by -metrics-addr
+// and -influx. Process is only set when grouping by process.
+type connGroup struct {
+	state, proto, process string
+}
+
+type groupCount struct {
+	connGroup
+	count int
+}
+
+// countGroups counts rows by state and protocol, and also by process name
+// when byProcess is set. The result is sorted by state, protocol and process.
+func countGroups(rows []render.Row, byProcess bool) []groupCount {
+	counts := make(map[connGroup]int)
+	for _, r := range rows {
+		g := connGroup{state: r.State, proto: r.Proto}
+		if byProcess {
+			g.process = r.Process
+		}
+		counts[g]++
+	}
+	groups := make([]groupCount, 0, len(counts))
+	for g, n := range counts {
+		groups = append(groups, groupCount{g, n})
+	}
+	sort.Slice(groups, func(i, j int) bool {
+		a, b := groups[i], groups[j]
+		if a.state != b.state {
+			return a.state < b.state
+		}
+		if a.proto != b.proto {
+			return a.proto < b.proto
+		}
+		return a.process < b.process
+	})
+	return groups
+}
+
+// buildMetrics turns a refresh's rows into metric families.
+func buildMetrics(rows []render.Row, now time.Time) []metricFamily {
+	conns := metricFamily{name: "tcpwatch_connections", help: "TCP connections by state and protocol.", typ: "gauge"}
+	for _, g := range countGroups(rows, false) {
+		conns.samples = append(conns.samples, metricSample{
+			labels: [][2]string{{"state", g.state}, {"proto", g.proto}},
+			value:  float64(g.count),
+		})
+	}
+	refreshed := metricFamily{
+		name:    "tcpwatch_last_refresh_timestamp_seconds",
+		help:    "Time of the last successful refresh.",
+		typ:     "gauge",
+		unit:    "seconds",
+		samples: []metricSample{{value: float64(now.UnixNano()) / 1e9}},
+	}
+	return []metricFamily{conns, refreshed}
+}
+
+// writePrometheus writes families in the Prometheus text format 0.0.4.
+func writePrometheus(w io.Writer, families []metricFamily) error {
+	var b bytes.Buffer
+	for _, f := range families {
+		fmt.Fprintf(&b, "# HELP %s %s\n", f.name, escapeHelp(f.help))
+		fmt.Fprintf(&b, "# TYPE %s %s\n", f.name, f.typ)
+		writeSamples(&b, f)
+	}
+	_, err := w.Write(b.Bytes())
+	return err
+}
+
+// writeOpenMetrics writes families in the OpenMetrics 1.0 text format,
+// which adds # UNIT lines and requires the # EOF terminator.
+func writeOpenMetrics(w io.Writer, families []metricFamily) error {
+	var b bytes.Buffer
+	for _, f := range families {
+		fmt.Fprintf(&b, "# TYPE %s %s\n", f.name, f.typ)
+		if f.unit != "" {
+			fmt.Fprintf(&b, "# UNIT %s %s\n", f.name, f.unit)
+		}
+		fmt.Fprintf(&b, "# HELP %s %s\n", f.name, escapeHelp(f.help))
+		writeSamples(&b, f)
+	}
+	b.WriteString("# EOF\n")
+	_, err := w.Write(b.Bytes())
+	return err
+}
+
+func writeSamples(b *bytes.Buffer, f metricFamily) {
+	for _, s := range f.samples {
+		b.WriteString(f.name)
+		if len(s.labels) > 0 {
+			b.WriteByte('{')
+			for i, l := range s.labels {
+				if i > 0 {
+					b.WriteByte(',')
+				}
+				fmt.Fprintf(b, "%s=\"%s\"", l[0], escapeLabel(l[1]))
+			}
+			b.WriteByte('}')
+		}
+		b.WriteByte(' ')
+		b.WriteString(strconv.FormatFloat(s.value, 'g', -1, 64))
+		b.WriteByte('\n')
+	}
+}
+
+func escapeHelp(s string) string {
+	return strings.NewReplacer(`\`, `\\`, "\n", `\n`).Replace(s)
+}
+
+func escapeLabel(s string) string {
+	return strings.NewReplacer(`\`, `\\`, "\n", `\n`, `"`, `\"`).Replace(s)
+}
+
+// metricsExporter serves the latest refresh's metrics over HTTP for
+// -metrics-addr.
+type metricsExporter struct {
+	openMetrics bool
+
+	mu       sync.Mutex
+	families []metricFamily
+}
+
+func (e *metricsExporter) update(rows []render.Row, now time.Time) {
+	families := buildMetrics(rows, now)
+	e.mu.Lock()
+	e.families = families
+	e.mu.Unlock()
+}
+
+func (e *metricsExporter) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	e.mu.Lock()
+	families := e.families
+	e.mu.Unlock()
+
+	if e.openMetrics {
+		w.Header().Set("Content-Type", "application/openmetrics-text; version=1.0.0; charset=utf-8")
+		_ = writeOpenMetrics(w, families)
+		return
+	}
+	w.Header().Set("Content-Type", "text/plain; version=0.0.4; charset=utf-8")
+	_ = writePrometheus(w, families)
+}
+
+// serveMetrics starts an HTTP server for e on addr. Listening happens before
+// it returns so address errors are reported at startup.
+func serveMetrics(addr string, e *metricsExporter) error {
+	ln, err := net.Listen("tcp", addr)
+	if err != nil {
+		return fmt.Errorf("-metrics-addr: %w", err)
+	}
+	mux := http.NewServeMux()
+	mux.Handle("/metrics", e)
+	go func() {
+		_ = http.Serve(ln, mux)
+	}()
+	return nil
+}
@@ -0,0 +1,66 @@
+package main
+
+import (
+	"bytes"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/bulent/morzer/tools/tcpwatch/internal/render"
+)
+
+func TestWriteOpenMetrics(t *testing.T) {
+	now := time.Unix(1700000000, 500000000)
+	tests := []struct {
+		name string
+		rows []render.Row
+		want string
+	}{
+		{"rows", []render.Row{
+			{Proto: "tcp4", State: "ESTABLISHED"},
+			{Proto: "tcp4", State: "ESTABLISHED"},
+			{Proto: "tcp6", State: "LISTEN"},
+		}, `# TYPE tcpwatch_connections gauge
+# HELP tcpwatch_connections TCP connections by state and protocol.
+tcpwatch_connections{state="ESTABLISHED",proto="tcp4"} 2
+tcpwatch_connections{state="LISTEN",proto="tcp6"} 1
+# TYPE tcpwatch_last_refresh_timestamp_seconds gauge
+# UNIT tcpwatch_last_refresh_timestamp_seconds seconds
+# HELP tcpwatch_last_refresh_timestamp_seconds Time of the last successful refresh.
+tcpwatch_last_refresh_timestamp_seconds 1.7000000005e+09
+# EOF
+`},
+		{"no rows", nil, `# TYPE tcpwatch_connections gauge
+# HELP tcpwatch_connections TCP connections by state and protocol.
+# TYPE tcpwatch_last_refresh_timestamp_seconds gauge
+# UNIT tcpwatch_last_refresh_timestamp_seconds seconds
+# HELP tcpwatch_last_refresh_timestamp_seconds Time of the last successful refresh.
+tcpwatch_last_refresh_timestamp_seconds 1.7000000005e+09
+# EOF
+`},
+	}
+	for _, tt := range tests {
+		var b bytes.Buffer
+		if err := writeOpenMetrics(&b, buildMetrics(tt.rows, now)); err != nil {
+			t.Fatal(err)
+		}
+		if b.String() != tt.want {
+			t.Errorf("%s: got\n%s\nwant\n%s", tt.name, b.String(), tt.want)
+		}
+	}
+}
+
+// Before the first refresh the exporter has no families; the OpenMetrics
+// response must still be terminated.
+func TestMetricsExporterBeforeRefresh(t *testing.T) {
+	e := &metricsExporter{openMetrics: true}
+	rec := httptest.NewRecorder()
+	e.ServeHTTP(rec, httptest.NewRequest("GET", "/metrics", nil))
+	if got := rec.Body.String(); got != "# EOF\n" {
+		t.Errorf("body = %q, want only the # EOF terminator", got)
+	}
+	if ct := rec.Header().Get("Content-Type"); !strings.HasPrefix(ct, "application/openmetrics-text") {
+		t.Errorf("Content-Type = %q", ct)
+	}
+}
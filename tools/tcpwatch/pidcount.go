@@ -0,0 +1,68 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"sort"
+	"time"
+
+	"github.com/bulent/morzer/tools/tcpwatch/internal/render"
+)
+
+type pidCount struct {
+	Updated time.Time `json:"updated"`
+	PID     int32     `json:"pid"`
+	Process string    `json:"process,omitempty"`
+	Count   int       `json:"count"`
+}
+
+// printPIDCount prints one "timestamp pid count" line per PID each refresh,
+// which is the minimal data needed to graph a connection (fd) leak.
+func printPIDCount(w io.Writer, opts options, rows []render.Row) error {
+	now := time.Now()
+	counts := countByPID(rows, now)
+	if len(counts) == 0 && opts.pidFilter >= 0 {
+		// Keep emitting a sample for the watched PID so gaps read as zero.
+		counts = []pidCount{{Updated: now, PID: opts.pidFilter, Count: 0}}
+	}
+
+	if opts.jsonLines {
+		enc := json.NewEncoder(w)
+		for _, c := range counts {
+			if err := enc.Encode(c); err != nil {
+				return err
+			}
+		}
+		return nil
+	}
+
+	if opts.jsonOut {
+		enc := jsonOutEncoder(w, opts)
+		return enc.Encode(counts)
+	}
+
+	for _, c := range counts {
+		fmt.Fprintf(w, "%s %d %d\n", now.Format(time.RFC3339), c.PID, c.Count)
+	}
+	return nil
+}
+
+func countByPID(rows []render.Row, now time.Time) []pidCount {
+	byPID := make(map[int32]*pidCount)
+	for _, r := range rows {
+		c, ok := byPID[r.PID]
+		if !ok {
+			c = &pidCount{Updated: now, PID: r.PID, Process: r.Process}
+			byPID[r.PID] = c
+		}
+		c.Count++
+	}
+
+	out := make([]pidCount, 0, len(byPID))
+	for _, c := range byPID {
+		out = append(out, *c)
+	}
+	sort.Slice(out, func(i, j int) bool { return out[i].PID < out[j].PID })
+	return out
+}
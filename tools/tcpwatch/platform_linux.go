@@ -0,0 +1,13 @@
+//go:build linux
+
+package main
+
+const platformSupported = true
+
+func platformName() string {
+	return "Linux"
+}
+
+// elevateHint says how to run tcpwatch with enough privileges to see other
+// users' processes.
+const elevateHint = "run it with sudo"
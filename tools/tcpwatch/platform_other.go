@@ -0,0 +1,25 @@
+//go:build !darwin && !windows && !linux
+
+package main
+
+import (
+	"context"
+	"fmt"
+	"runtime"
+)
+
+// gopsutil only partially works on other platforms (e.g. the BSDs), so
+// tcpwatch runs there with reduced functionality.
+const platformSupported = false
+
+// elevateHint says how to run tcpwatch with enough privileges to see other
+// users' processes.
+const elevateHint = "run it as root"
+
+func platformName() string {
+	return "unsupported"
+}
+
+func psComm(ctx context.Context, pid int32) (string, error) {
+	return "", fmt.Errorf("process name fallback not available on %s", runtime.GOOS)
+}
@@ -0,0 +1,24 @@
+//go:build darwin || linux
+
+package main
+
+import (
+	"context"
+	"fmt"
+	"os/exec"
+	"path/filepath"
+	"strings"
+)
+
+func psComm(ctx context.Context, pid int32) (string, error) {
+	cmd := exec.CommandContext(ctx, "ps", "-p", fmt.Sprint(pid), "-o", "comm=")
+	out, err := cmd.Output()
+	if err != nil {
+		return "", err
+	}
+	name := strings.TrimSpace(string(out))
+	if name == "" {
+		return "", fmt.Errorf("ps returned empty comm")
+	}
+	return filepath.Base(name), nil
+}
@@ -0,0 +1,22 @@
+//go:build windows
+
+package main
+
+import (
+	"context"
+	"fmt"
+)
+
+const platformSupported = true
+
+func platformName() string {
+	return "Windows"
+}
+
+func psComm(ctx context.Context, pid int32) (string, error) {
+	return "", fmt.Errorf("ps fallback not available on Windows")
+}
+
+// elevateHint says how to run tcpwatch with enough privileges to see other
+// users' processes.
+const elevateHint = "run it from an Administrator prompt"
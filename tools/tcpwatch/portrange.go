@@ -0,0 +1,68 @@
+package main
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+
+	"github.com/bulent/morzer/tools/tcpwatch/internal/render"
+)
+
+// portRange is an inclusive range of TCP ports.
+type portRange struct {
+	lo, hi uint32
+}
+
+// portRanges is a set of port ranges parsed from a list like "25,465,8000-8100".
+type portRanges []portRange
+
+func parsePortRanges(s string) (portRanges, error) {
+	var rs portRanges
+	for _, part := range strings.Split(s, ",") {
+		part = strings.TrimSpace(part)
+		if part == "" {
+			continue
+		}
+		lo, hi, isRange := strings.Cut(part, "-")
+		if !isRange {
+			hi = lo
+		}
+		l, err1 := strconv.ParseUint(lo, 10, 16)
+		h, err2 := strconv.ParseUint(hi, 10, 16)
+		if err1 != nil || err2 != nil || l == 0 || l > h {
+			return nil, fmt.Errorf("invalid port or range %q", part)
+		}
+		rs = append(rs, portRange{lo: uint32(l), hi: uint32(h)})
+	}
+	if len(rs) == 0 {
+		return nil, fmt.Errorf("no ports given")
+	}
+	return rs, nil
+}
+
+func (rs portRanges) contains(port uint32) bool {
+	for _, r := range rs {
+		if port >= r.lo && port <= r.hi {
+			return true
+		}
+	}
+	return false
+}
+
+// containsRow reports whether the local or remote port of r is in rs.
+func (rs portRanges) containsRow(r render.Row) bool {
+	return rs.contains(addrPort(r.Local)) || rs.contains(addrPort(r.Remote))
+}
+
+// addrPort returns the port of a formatted "host:port" address, or 0.
+func addrPort(addr string) uint32 {
+	i := strings.LastIndexByte(addr, ':')
+	if i < 0 {
+		return 0
+	}
+	p, err := strconv.ParseUint(addr[i+1:], 10, 16)
+	if err != nil {
+		return 0
+	}
+	return uint32(p)
+}
@@ -0,0 +1,43 @@
+package main
+
+import (
+	"fmt"
+	"io"
+
+	"github.com/bulent/morzer/tools/tcpwatch/internal/render"
+)
+
+// Without elevated privileges the OS hides the owners of other users'
+// sockets. When at least privHintMinRows rows could have an owner and more
+// than privHintFraction of them have none, tcpwatch suggests elevating.
+const (
+	privHintMinRows  = 10
+	privHintFraction = 0.5
+)
+
+// mostlyOwnerless reports whether rows look like they were listed without
+// the privileges to see their processes: no PID, or (as on Windows and on
+// macOS without root) a PID whose name can't be looked up. TIME_WAIT sockets
+// never have an owner and aren't counted.
+func mostlyOwnerless(rows []render.Row) bool {
+	total, missing := 0, 0
+	for _, r := range rows {
+		if r.State == "TIME_WAIT" {
+			continue
+		}
+		total++
+		if r.PID <= 0 || r.Process == "" {
+			missing++
+		}
+	}
+	return total >= privHintMinRows && float64(missing) > privHintFraction*float64(total)
+}
+
+// privilegeHint prints a one-time hint on w when most rows lack a process.
+func (st *watchState) privilegeHint(w io.Writer, rows []render.Row) {
+	if st.privHinted || !mostlyOwnerless(rows) {
+		return
+	}
+	st.privHinted = true
+	fmt.Fprintf(w, "tcpwatch: most connections have no PID or process; the OS hides other users' sockets from unprivileged processes, %s to see them\n", elevateHint)
+}
@@ -0,0 +1,90 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"syscall"
+	"testing"
+	"time"
+
+	"github.com/bulent/morzer/tools/tcpwatch/internal/render"
+	gnet "github.com/shirou/gopsutil/v4/net"
+)
+
+// hintRows returns n rows in state, the first owned of them with a PID and
+// process name, the rest as unowned returns.
+func hintRows(n, owned int, state string, unowned render.Row) []render.Row {
+	rows := make([]render.Row, n)
+	for i := range rows {
+		rows[i] = unowned
+		if i < owned {
+			rows[i] = render.Row{PID: 100, Process: "nginx"}
+		}
+		rows[i].State = state
+	}
+	return rows
+}
+
+func TestMostlyOwnerless(t *testing.T) {
+	noPID := render.Row{PID: 0}
+	noName := render.Row{PID: 4242}
+	tests := []struct {
+		name string
+		rows []render.Row
+		want bool
+	}{
+		{"no PIDs", hintRows(10, 2, "ESTABLISHED", noPID), true},
+		{"PIDs without names", hintRows(10, 2, "ESTABLISHED", noName), true},
+		{"mostly owned", hintRows(10, 6, "ESTABLISHED", noName), false},
+		{"half owned", hintRows(10, 5, "ESTABLISHED", noPID), false},
+		{"too few rows", hintRows(9, 0, "ESTABLISHED", noPID), false},
+		{"TIME_WAIT ignored", append(hintRows(10, 10, "ESTABLISHED", noPID), hintRows(50, 0, "TIME_WAIT", noPID)...), false},
+		{"only TIME_WAIT", hintRows(50, 0, "TIME_WAIT", noPID), false},
+	}
+	for _, tt := range tests {
+		if got := mostlyOwnerless(tt.rows); got != tt.want {
+			t.Errorf("%s: mostlyOwnerless = %v, want %v", tt.name, got, tt.want)
+		}
+	}
+}
+
+func TestPrivilegeHintOnce(t *testing.T) {
+	st := &watchState{}
+	var b bytes.Buffer
+	rows := hintRows(10, 0, "ESTABLISHED", render.Row{PID: 7})
+	st.privilegeHint(&b, rows)
+	first := b.Len()
+	st.privilegeHint(&b, rows)
+	if first == 0 || b.Len() != first {
+		t.Errorf("hint written %d then %d bytes, want once", first, b.Len()-first)
+	}
+}
+
+// listTCP notes when -resolve-budget left names unresolved, which runOnce
+// uses to hold back the privilege hint.
+func TestResolveBudgetHit(t *testing.T) {
+	src := fakeSource{}
+	for i := range 20 {
+		src.conns = append(src.conns, tcpConn(syscall.AF_INET, gnet.Addr{IP: "10.0.0.5", Port: uint32(40000 + i)}, gnet.Addr{IP: "10.0.0.9", Port: 443}, "ESTABLISHED", int32(1<<30+i)))
+	}
+	for _, tt := range []struct {
+		budget string
+		want   bool
+	}{
+		{"0", false},
+		{"1ns", true},
+	} {
+		opts, err := parseFlags([]string{"-resolve-budget", tt.budget})
+		if err != nil {
+			t.Fatal(err)
+		}
+		opts.source = src
+		procs := newProcResolver(time.Minute, opts.procCacheSize, opts.resolveMethod)
+		if _, err := listTCP(context.Background(), opts, procs); err != nil {
+			t.Fatal(err)
+		}
+		if procs.budgetHit != tt.want {
+			t.Errorf("-resolve-budget %s: budgetHit = %v, want %v", tt.budget, procs.budgetHit, tt.want)
+		}
+	}
+}
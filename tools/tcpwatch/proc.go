@@ -0,0 +1,287 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"math"
+	"path/filepath"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+
+	gproc "github.com/shirou/gopsutil/v4/process"
+)
+
+type procCacheEntry struct {
+	name  string
+	until time.Time
+}
+
+// Process name resolution methods for -proc-resolve-method.
+const (
+	resolveGopsutil = "gopsutil" // gopsutil, then ps when it fails
+	resolveOS       = "os"       // ps only
+	resolveOSFirst  = "os-first" // ps, then gopsutil when it fails
+)
+
+type procResolver struct {
+	ttl    time.Duration
+	method string
+	cache  *procCache
+	// exes, cmdlines and users are only filled when an option asks for
+	// them.
+	exes     *procCache
+	cmdlines *procCache
+	users    *procCache
+	limits   *procCache
+	cwds     *procCache
+	ppids    *procCache
+	// unsigned caches -unsigned-only results per executable path; a binary's
+	// signature doesn't change while it runs, so entries don't expire.
+	unsigned map[string]bool
+	// startTime looks up a process's start time in Unix milliseconds.
+	startTime func(ctx context.Context, pid int32) (int64, error)
+	// budgetHit reports whether the latest listTCP left names unresolved
+	// because -resolve-budget ran out.
+	budgetHit bool
+}
+
+// newProcResolver returns a resolver caching lookups for ttl, keeping at most
+// size PIDs per kind of lookup. method picks how names are resolved.
+func newProcResolver(ttl time.Duration, size int, method string) *procResolver {
+	return &procResolver{
+		ttl:       ttl,
+		method:    method,
+		cache:     newProcCache(size),
+		exes:      newProcCache(size),
+		cmdlines:  newProcCache(size),
+		users:     newProcCache(size),
+		limits:    newProcCache(size),
+		cwds:      newProcCache(size),
+		ppids:     newProcCache(size),
+		unsigned:  make(map[string]bool),
+		startTime: processStartTime,
+	}
+}
+
+func (r *procResolver) Name(ctx context.Context, pid int32) string {
+	if name, ok := r.CachedName(pid); ok {
+		return name
+	}
+
+	name := ""
+	switch r.method {
+	case resolveOS:
+		name = r.osName(ctx, pid)
+	case resolveOSFirst:
+		if name = r.osName(ctx, pid); name == "" {
+			name = r.gopsutilName(ctx, pid)
+		}
+	default:
+		if name = r.gopsutilName(ctx, pid); name == "" {
+			name = r.osName(ctx, pid)
+		}
+	}
+
+	name = strings.TrimSpace(name)
+	ttl := r.ttl
+	if (name == "" || isDefunct(name)) && isExiting(ctx, pid, name) {
+		// Dying processes are retried soon: by then they have either gone
+		// or settled.
+		name = exitingName(ctx, pid, name)
+		ttl = min(ttl, exitingTTL)
+	}
+	r.cache.put(pid, procCacheEntry{name: name, until: time.Now().Add(ttl)})
+	return name
+}
+
+// exitingTTL is how long the name of a zombie or vanished process stays
+// cached.
+const exitingTTL = 2 * time.Second
+
+// isDefunct reports whether name is how ps labels a zombie, e.g.
+// "node <defunct>".
+func isDefunct(name string) bool {
+	return strings.Contains(name, "<defunct>") || strings.Contains(name, "(defunct)")
+}
+
+// isExiting reports whether pid, whose name resolved as name, is a zombie or
+// already gone. A name that is merely unreadable, as for another user's
+// process on macOS, doesn't make a process exiting.
+func isExiting(ctx context.Context, pid int32, name string) bool {
+	if isDefunct(name) {
+		return true
+	}
+	p, err := gproc.NewProcessWithContext(ctx, pid)
+	if err != nil {
+		return errors.Is(err, gproc.ErrorProcessNotRunning)
+	}
+	status, err := p.StatusWithContext(ctx)
+	return err == nil && slices.Contains(status, gproc.Zombie)
+}
+
+// exitingName names a process whose name resolved empty or as a zombie:
+// the name without the defunct marker, or else the base name of its
+// executable or command line, followed by " (exiting)". It returns "" when
+// none of them is available, e.g. for another user's process.
+func exitingName(ctx context.Context, pid int32, name string) string {
+	name = strings.TrimSpace(strings.NewReplacer("<defunct>", "", "(defunct)", "").Replace(name))
+	if name == "" {
+		if p, err := gproc.NewProcessWithContext(ctx, pid); err == nil {
+			if exe, err := p.ExeWithContext(ctx); err == nil && exe != "" {
+				name = filepath.Base(exe)
+			} else if args, err := p.CmdlineSliceWithContext(ctx); err == nil && len(args) > 0 && args[0] != "" {
+				name = filepath.Base(args[0])
+			}
+		}
+	}
+	if name == "" {
+		return ""
+	}
+	return name + " (exiting)"
+}
+
+// CachedName returns pid's name if a recent lookup is cached, without
+// resolving it otherwise.
+func (r *procResolver) CachedName(pid int32) (string, bool) {
+	if pid <= 0 {
+		return "", true
+	}
+	if ent, ok := r.cache.get(pid); ok && time.Now().Before(ent.until) {
+		return ent.name, true
+	}
+	return "", false
+}
+
+func (r *procResolver) gopsutilName(ctx context.Context, pid int32) string {
+	p, err := gproc.NewProcess(pid)
+	if err != nil {
+		return ""
+	}
+	n, err := p.NameWithContext(ctx)
+	if err != nil {
+		return ""
+	}
+	return strings.TrimSpace(n)
+}
+
+// osName asks the OS tool (ps) for the name.
+func (r *procResolver) osName(ctx context.Context, pid int32) string {
+	n, err := psComm(ctx, pid)
+	if err != nil {
+		return ""
+	}
+	return n
+}
+
+// Exe returns the executable path of pid, or "" if unavailable.
+func (r *procResolver) Exe(ctx context.Context, pid int32) string {
+	return r.detail(ctx, r.exes, pid, (*gproc.Process).ExeWithContext)
+}
+
+// Cmdline returns the full command line of pid, or "" if unavailable.
+func (r *procResolver) Cmdline(ctx context.Context, pid int32) string {
+	return r.detail(ctx, r.cmdlines, pid, (*gproc.Process).CmdlineWithContext)
+}
+
+// User returns the name of the user running pid, or "" if unavailable.
+func (r *procResolver) User(ctx context.Context, pid int32) string {
+	return r.detail(ctx, r.users, pid, (*gproc.Process).UsernameWithContext)
+}
+
+// Cwd returns the current working directory of pid, or "" if unavailable
+// (other users' processes usually need elevated privileges).
+func (r *procResolver) Cwd(ctx context.Context, pid int32) string {
+	return r.detail(ctx, r.cwds, pid, (*gproc.Process).CwdWithContext)
+}
+
+// PPID returns the parent PID of pid, or 0 if unavailable.
+func (r *procResolver) PPID(ctx context.Context, pid int32) int32 {
+	v := r.detail(ctx, r.ppids, pid, func(p *gproc.Process, ctx context.Context) (string, error) {
+		ppid, err := p.PpidWithContext(ctx)
+		return strconv.Itoa(int(ppid)), err
+	})
+	n, _ := strconv.ParseInt(v, 10, 32)
+	return int32(n)
+}
+
+// FDLimit returns the soft limit on open files of pid, or 0 if it is
+// unlimited or can't be read (gopsutil only reads limits on Linux).
+func (r *procResolver) FDLimit(ctx context.Context, pid int32) uint64 {
+	v := r.detail(ctx, r.limits, pid, func(p *gproc.Process, ctx context.Context) (string, error) {
+		limits, err := p.RlimitWithContext(ctx)
+		if err != nil {
+			return "", err
+		}
+		for _, l := range limits {
+			if l.Resource == gproc.RLIMIT_NOFILE && l.Soft != math.MaxUint64 {
+				return strconv.FormatUint(l.Soft, 10), nil
+			}
+		}
+		return "", nil
+	})
+	n, _ := strconv.ParseUint(v, 10, 64)
+	return n
+}
+
+// Started returns the start time of pid in Unix milliseconds, or 0 if
+// unavailable. It isn't cached: a cached value would hide exactly the PID
+// reuse callers want to detect.
+func (r *procResolver) Started(ctx context.Context, pid int32) int64 {
+	if pid <= 0 {
+		return 0
+	}
+	t, err := r.startTime(ctx, pid)
+	if err != nil {
+		return 0
+	}
+	return t
+}
+
+func processStartTime(ctx context.Context, pid int32) (int64, error) {
+	p, err := gproc.NewProcess(pid)
+	if err != nil {
+		return 0, err
+	}
+	return p.CreateTimeWithContext(ctx)
+}
+
+func (r *procResolver) detail(ctx context.Context, cache *procCache, pid int32, get func(*gproc.Process, context.Context) (string, error)) string {
+	if pid <= 0 {
+		return ""
+	}
+
+	if ent, ok := cache.get(pid); ok && time.Now().Before(ent.until) {
+		return ent.name
+	}
+
+	v := ""
+	if p, err := gproc.NewProcess(pid); err == nil {
+		if s, err := get(p, ctx); err == nil {
+			v = strings.TrimSpace(s)
+		}
+	}
+	cache.put(pid, procCacheEntry{name: v, until: time.Now().Add(r.ttl)})
+	return v
+}
+
+// Unsigned reports whether pid's executable is unsigned or ad-hoc signed. It
+// reports false when the executable or its signature can't be determined.
+func (r *procResolver) Unsigned(ctx context.Context, pid int32) bool {
+	exe := r.Exe(ctx, pid)
+	if exe == "" {
+		return false
+	}
+	if v, ok := r.unsigned[exe]; ok {
+		return v
+	}
+	v, err := unsignedExe(ctx, exe)
+	if err != nil {
+		// Don't cache failures (e.g. a canceled context); the next refresh
+		// retries.
+		return false
+	}
+	r.unsigned[exe] = v
+	return v
+}
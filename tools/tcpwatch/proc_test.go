@@ -0,0 +1,21 @@
+package main
+
+import (
+	"context"
+	"os"
+	"testing"
+)
+
+func TestIsExiting(t *testing.T) {
+	ctx := context.Background()
+	self := int32(os.Getpid())
+	if isExiting(ctx, self, "") {
+		t.Error("a running process with an unreadable name counts as exiting")
+	}
+	if !isExiting(ctx, self, "node <defunct>") {
+		t.Error("a defunct name doesn't count as exiting")
+	}
+	if !isExiting(ctx, 1<<30, "") {
+		t.Error("a PID that doesn't exist doesn't count as exiting")
+	}
+}
@@ -0,0 +1,44 @@
+package main
+
+import "container/list"
+
+// procCache is a least-recently-used map from PID to cached lookups, capped
+// at size entries so long runs on hosts with heavy PID churn don't grow it
+// without bound. Entries still expire by their until time.
+type procCache struct {
+	size  int
+	order *list.List // front is most recently used; values are *procCacheItem
+	items map[int32]*list.Element
+}
+
+type procCacheItem struct {
+	pid   int32
+	entry procCacheEntry
+}
+
+func newProcCache(size int) *procCache {
+	return &procCache{size: size, order: list.New(), items: make(map[int32]*list.Element)}
+}
+
+func (c *procCache) get(pid int32) (procCacheEntry, bool) {
+	el, ok := c.items[pid]
+	if !ok {
+		return procCacheEntry{}, false
+	}
+	c.order.MoveToFront(el)
+	return el.Value.(*procCacheItem).entry, true
+}
+
+func (c *procCache) put(pid int32, ent procCacheEntry) {
+	if el, ok := c.items[pid]; ok {
+		el.Value.(*procCacheItem).entry = ent
+		c.order.MoveToFront(el)
+		return
+	}
+	c.items[pid] = c.order.PushFront(&procCacheItem{pid: pid, entry: ent})
+	for c.order.Len() > c.size {
+		oldest := c.order.Back()
+		c.order.Remove(oldest)
+		delete(c.items, oldest.Value.(*procCacheItem).pid)
+	}
+}
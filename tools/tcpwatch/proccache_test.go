@@ -0,0 +1,41 @@
+package main
+
+import "testing"
+
+func TestProcCacheEvictsLeastRecentlyUsed(t *testing.T) {
+	c := newProcCache(3)
+	for pid := int32(1); pid <= 3; pid++ {
+		c.put(pid, procCacheEntry{name: "p"})
+	}
+	c.get(1)                               // 1 is now the most recent; 2 the least
+	c.put(3, procCacheEntry{name: "new3"}) // updating an entry also counts as a use
+	c.put(4, procCacheEntry{name: "p4"})
+
+	if n := c.order.Len(); n != 3 || len(c.items) != 3 {
+		t.Fatalf("cache holds %d list / %d map entries, want 3", n, len(c.items))
+	}
+	if _, ok := c.get(2); ok {
+		t.Error("PID 2, the least recently used, was not evicted")
+	}
+	for _, pid := range []int32{1, 3, 4} {
+		if _, ok := c.get(pid); !ok {
+			t.Errorf("PID %d was evicted", pid)
+		}
+	}
+	if ent, _ := c.get(3); ent.name != "new3" {
+		t.Errorf("PID 3 = %q, want the updated entry", ent.name)
+	}
+
+	// Many more PIDs than the cap never grow it.
+	for pid := int32(100); pid < 1100; pid++ {
+		c.put(pid, procCacheEntry{})
+	}
+	if n := c.order.Len(); n != 3 || len(c.items) != 3 {
+		t.Errorf("after 1000 puts the cache holds %d list / %d map entries, want 3", n, len(c.items))
+	}
+	for _, pid := range []int32{1097, 1098, 1099} {
+		if _, ok := c.get(pid); !ok {
+			t.Errorf("PID %d, one of the newest, was evicted", pid)
+		}
+	}
+}
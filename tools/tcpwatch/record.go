@@ -0,0 +1,53 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+
+	"github.com/bulent/morzer/tools/tcpwatch/internal/render"
+)
+
+// recorder appends every refresh's rows to a -record file in the -replay
+// format. Each snapshot is written as soon as it is taken; the closing
+// bracket is added by close, and loadReplay accepts a file without it, so
+// a recording cut short by a crash still plays back.
+type recorder struct {
+	f     *os.File
+	count int
+}
+
+func newRecorder(path string) (*recorder, error) {
+	f, err := os.Create(path)
+	if err != nil {
+		return nil, fmt.Errorf("-record: %w", err)
+	}
+	if _, err := f.WriteString("[\n"); err != nil {
+		f.Close()
+		return nil, fmt.Errorf("-record: %w", err)
+	}
+	return &recorder{f: f}, nil
+}
+
+func (r *recorder) write(rows []render.Row) error {
+	data, err := json.Marshal(rows)
+	if err != nil {
+		return err
+	}
+	if r.count > 0 {
+		data = append([]byte(",\n"), data...)
+	}
+	if _, err := r.f.Write(data); err != nil {
+		return fmt.Errorf("-record: %w", err)
+	}
+	r.count++
+	return nil
+}
+
+func (r *recorder) close() error {
+	if _, err := r.f.WriteString("\n]\n"); err != nil {
+		r.f.Close()
+		return fmt.Errorf("-record: %w", err)
+	}
+	return r.f.Close()
+}
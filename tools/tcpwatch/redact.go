@@ -0,0 +1,82 @@
+package main
+
+import (
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"net"
+	"strings"
+)
+
+// redactor masks the host part of addresses so output can be shared in bug
+// reports. Private addresses keep their leading octet/group so the network
+// type stays recognisable; public addresses become a hash that is consistent
+// within a run (salted per process so the IPv4 space can't simply be
+// enumerated to reverse it). Ports, loopback and wildcard addresses are kept.
+type redactor struct {
+	salt [16]byte
+}
+
+func newRedactor() *redactor {
+	r := &redactor{}
+	_, _ = rand.Read(r.salt[:])
+	return r
+}
+
+func (r *redactor) Addr(addr string) string {
+	host, port, err := net.SplitHostPort(addr)
+	if err != nil {
+		// formatAddr leaves IPv4-mapped IPv6 addresses unbracketed
+		// (::ffff:10.0.0.1:80), which SplitHostPort rejects; the port
+		// follows the last colon all the same. Without any colon the whole
+		// string is masked rather than leaked.
+		i := strings.LastIndexByte(addr, ':')
+		if i < 0 {
+			return r.host(addr)
+		}
+		host, port = addr[:i], addr[i+1:]
+	}
+	masked := r.host(host)
+	if masked == host {
+		return addr
+	}
+	if strings.Contains(masked, ":") {
+		return "[" + masked + "]:" + port
+	}
+	return masked + ":" + port
+}
+
+func (r *redactor) host(host string) string {
+	// Interface zones (fe80::1%en0) aren't sensitive; mask the address only.
+	host, zone, _ := strings.Cut(host, "%")
+	if zone != "" {
+		return r.host(host) + "%" + zone
+	}
+
+	ip := net.ParseIP(host)
+	if ip == nil {
+		if host == "*" || host == "" {
+			return host
+		}
+		// Unparseable; never leak it verbatim.
+		return r.hash(host)
+	}
+	if ip.IsLoopback() || ip.IsUnspecified() {
+		return host
+	}
+
+	if ip.IsPrivate() || ip.IsLinkLocalUnicast() {
+		if v4 := ip.To4(); v4 != nil {
+			return strings.SplitN(v4.String(), ".", 2)[0] + ".x.x.x"
+		}
+		return strings.SplitN(ip.String(), ":", 2)[0] + "::x"
+	}
+	return r.hash(host)
+}
+
+func (r *redactor) hash(host string) string {
+	h := sha256.New()
+	h.Write(r.salt[:])
+	h.Write([]byte(host))
+	return "ip-" + hex.EncodeToString(h.Sum(nil)[:4])
+}
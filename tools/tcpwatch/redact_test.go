@@ -0,0 +1,41 @@
+package main
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestRedactAddr(t *testing.T) {
+	r := newRedactor()
+	tests := []struct {
+		addr string
+		want string // "" means hashed: an ip- label with the port kept
+		port string
+	}{
+		{"10.1.2.3:22", "10.x.x.x:22", ""},
+		{"::ffff:10.0.0.1:80", "10.x.x.x:80", ""},
+		{"::ffff:93.184.216.34:443", "", ":443"},
+		{"93.184.216.34:443", "", ":443"},
+		{"[2001:db8::1]:443", "", ":443"},
+		{"[fe80::1%eth0]:22", "[fe80::x%eth0]:22", ""},
+		{"127.0.0.1:8080", "127.0.0.1:8080", ""},
+		{"::ffff:127.0.0.1:8080", "::ffff:127.0.0.1:8080", ""},
+		{"*:*", "*:*", ""},
+		{"93.184.216.34", "", ""},
+	}
+	for _, tt := range tests {
+		got := r.Addr(tt.addr)
+		if tt.want != "" {
+			if got != tt.want {
+				t.Errorf("Addr(%q) = %q, want %q", tt.addr, got, tt.want)
+			}
+			continue
+		}
+		if !strings.HasPrefix(strings.TrimPrefix(got, "["), "ip-") || !strings.HasSuffix(got, tt.port) || strings.Contains(got, "93.184") || strings.Contains(got, "db8") {
+			t.Errorf("Addr(%q) = %q, want a hashed host with port %q", tt.addr, got, tt.port)
+		}
+	}
+	if a, b := r.Addr("::ffff:93.184.216.34:443"), r.Addr("::ffff:93.184.216.34:443"); a != b {
+		t.Errorf("hashes differ within a run: %q, %q", a, b)
+	}
+}
@@ -0,0 +1,14 @@
+//go:build !windows
+
+package main
+
+import (
+	"os"
+	"syscall"
+)
+
+// reloadSignals are the signals that make tcpwatch reread
+// -watch-ignore-file.
+func reloadSignals() []os.Signal {
+	return []os.Signal{syscall.SIGHUP}
+}
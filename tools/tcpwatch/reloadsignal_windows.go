@@ -0,0 +1,10 @@
+//go:build windows
+
+package main
+
+import "os"
+
+// Windows has no SIGHUP, so -watch-ignore-file is only read at startup.
+func reloadSignals() []os.Signal {
+	return nil
+}
@@ -0,0 +1,35 @@
+package main
+
+import (
+	"io"
+	"time"
+
+	"github.com/bulent/morzer/tools/tcpwatch/internal/render"
+)
+
+// eventRemotePort is the -watch-only-remote-port event kind.
+const eventRemotePort = "remote-port"
+
+// printRemotePortAlerts reports connections to one of the watched remote
+// ports. Each connection alerts once, when it is first seen; connections
+// already open at startup alert on the first refresh.
+func printRemotePortAlerts(w io.Writer, opts options, st *watchState, rows []render.Row) error {
+	now := time.Now()
+	seen := make(map[connKey]struct{})
+	var events []connEvent
+	for _, r := range rows {
+		if r.Listening || !opts.remotePorts.contains(addrPort(r.Remote)) {
+			continue
+		}
+		k := opts.identity.key(r)
+		if _, dup := seen[k]; dup {
+			continue
+		}
+		seen[k] = struct{}{}
+		if _, ok := st.remoteSeen[k]; !ok {
+			events = append(events, connEvent{Updated: now, Kind: eventRemotePort, Row: r})
+		}
+	}
+	st.remoteSeen = seen
+	return printAlerts(w, opts, events)
+}
@@ -0,0 +1,160 @@
+package main
+
+import (
+	"fmt"
+	"io"
+	"time"
+
+	"github.com/bulent/morzer/tools/tcpwatch/internal/render"
+)
+
+// renderer writes one refresh's connection listing in an output format.
+// printRows selects one with newRenderer on the first refresh and reuses it,
+// so a renderer can keep state between refreshes.
+type renderer interface {
+	render(w io.Writer, snap jsonSnapshot) error
+}
+
+// newRenderer returns the renderer for opts.format.
+func newRenderer(opts options, st *watchState) renderer {
+	switch opts.format {
+	case formatJSON:
+		return jsonRenderer{opts: opts}
+	case formatJSONL:
+		return jsonlRenderer{opts: opts, st: st}
+	case formatCSV, formatTSV:
+		return &csvRenderer{opts: opts}
+	case formatInflux:
+		return influxRenderer{}
+	case formatPrometheus:
+		return prometheusRenderer{}
+	}
+	return &tableRenderer{opts: opts, st: st}
+}
+
+// tableRenderer draws the aligned table, or the -tree, -sectioned or -sep
+// layouts of it, clearing the screen before every refresh unless -no-clear.
+type tableRenderer struct {
+	opts options
+	st   *watchState
+	// headerShown is set once the header has been printed
+	// (-no-header-repeat).
+	headerShown bool
+}
+
+func (r *tableRenderer) render(w io.Writer, snap jsonSnapshot) error {
+	opts := r.opts
+	if !opts.noClear {
+		clearScreen(w, opts, r.st)
+	}
+
+	topts := render.Options{
+		ShowHeader:      opts.header && !(opts.headerOnce && r.headerShown),
+		Now:             snap.Updated,
+		Title:           snap.Title,
+		CollapseProcess: opts.collapse,
+		Highlight:       opts.highlight,
+		Color:           opts.color,
+		ShowDir:         opts.direction != "",
+		ShowService:     opts.serviceNames,
+		Layout:          opts.layout,
+		StateCodes:      opts.stateCodes,
+		ShowSeq:         opts.seq,
+		ShowAge:         opts.age,
+		ShowInState:     opts.stuck > 0,
+		ShowSockDiag:    opts.sockDiag,
+		ShowFDs:         opts.fdWarnPercent > 0,
+		ShowListener:    opts.showListener,
+		ShowCwd:         opts.showCwd,
+		ShowCounters:    opts.counters,
+		ResolveBoth:     opts.resolveBoth,
+		LinePrefix:      expandLinePrefix(opts, snap.Updated),
+		ShowHost:        opts.hostLabel != "" || opts.aggregate,
+		Columns:         opts.columns,
+		Width:           opts.width,
+		MaxRemoteWidth:  opts.maxRemoteWidth,
+		HumanDurations:  opts.human,
+		SortKey:         opts.sortKey,
+		EmptyMessage:    emptyMessage(opts),
+		Separator:       opts.separator,
+	}
+	rows := snap.Rows
+	if opts.grep != nil {
+		rows = grepRows(rows, opts.grep, topts)
+		if !opts.grepKeepHeader {
+			topts.Title, topts.Now, topts.ShowHeader, topts.EmptyMessage = "", time.Time{}, false, ""
+		}
+	}
+	if opts.tree {
+		render.PrintTree(w, rows, topts)
+	} else if opts.sectioned {
+		render.PrintSectioned(w, rows, topts)
+	} else {
+		render.PrintTable(w, rows, topts)
+	}
+	r.headerShown = r.headerShown || topts.ShowHeader
+	if opts.warnPorts != nil {
+		_, err := fmt.Fprintf(w, "%d connection(s) on a -warn-port port\n", snap.warnCount)
+		return err
+	}
+	return nil
+}
+
+// jsonRenderer writes the rows as a JSON array (-json).
+type jsonRenderer struct {
+	opts options
+}
+
+func (r jsonRenderer) render(w io.Writer, snap jsonSnapshot) error {
+	return jsonOutEncoder(w, r.opts).Encode(snap.Rows)
+}
+
+// jsonlRenderer writes the whole snapshot as one NDJSON line (-jsonl).
+type jsonlRenderer struct {
+	opts options
+	st   *watchState
+}
+
+func (r jsonlRenderer) render(w io.Writer, snap jsonSnapshot) error {
+	return writeJSONLSnapshot(w, r.opts, r.st, snap)
+}
+
+// csvRenderer writes CSV, or TSV for -format tsv. In watch mode the stream
+// gets its header (and -csv-comment line) once.
+type csvRenderer struct {
+	opts    options
+	started bool
+}
+
+func (r *csvRenderer) render(w io.Writer, snap jsonSnapshot) error {
+	first := !r.started
+	r.started = true
+	return render.PrintCSV(w, snap.Rows, render.Options{
+		ShowHeader:  r.opts.header && first,
+		Now:         snap.Updated,
+		ShowDir:     r.opts.direction != "",
+		ShowService: r.opts.serviceNames,
+		TypeComment: r.opts.csvComment && first,
+		CSVComma:    csvComma(r.opts.format),
+		ShowSeq:     r.opts.seq,
+		ShowAge:     r.opts.age,
+		ShowHost:    r.opts.hostLabel != "" || r.opts.aggregate,
+		SortKey:     r.opts.sortKey,
+	})
+}
+
+// influxRenderer writes connection counts as InfluxDB line protocol
+// (-influx).
+type influxRenderer struct{}
+
+func (influxRenderer) render(w io.Writer, snap jsonSnapshot) error {
+	return printInflux(w, snap.Rows, snap.Updated)
+}
+
+// prometheusRenderer writes connection counts in the text exposition format
+// served by -metrics-addr (-format prometheus).
+type prometheusRenderer struct{}
+
+func (prometheusRenderer) render(w io.Writer, snap jsonSnapshot) error {
+	return writePrometheus(w, buildMetrics(snap.Rows, snap.Updated))
+}
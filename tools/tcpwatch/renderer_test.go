@@ -0,0 +1,196 @@
+package main
+
+import (
+	"bytes"
+	"fmt"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/bulent/morzer/tools/tcpwatch/internal/render"
+)
+
+// renderSnap is the fixed snapshot every renderer test draws.
+func renderSnap() jsonSnapshot {
+	return jsonSnapshot{
+		Updated: time.Date(2026, 1, 2, 3, 4, 5, 0, time.UTC),
+		Title:   "Live TCP connections",
+		Rows: []render.Row{
+			{Proto: "tcp4", Local: "10.0.0.5:51000", Remote: "93.184.216.34:443", State: "ESTABLISHED", PID: 100, Process: "curl"},
+			{Proto: "tcp6", Local: "[::]:22", Remote: "[::]:0", State: "LISTEN", PID: 7, Process: "sshd", Listening: true},
+		},
+	}
+}
+
+func TestRenderers(t *testing.T) {
+	tests := []struct {
+		args []string
+		want string
+	}{
+		{[]string{"-no-clear"}, `Live TCP connections
+Updated:  2026-01-02T03:04:05Z
+PROTO     LOCAL           REMOTE             STATE        PID  PROCESS
+tcp4      10.0.0.5:51000  93.184.216.34:443  ESTABLISHED  100  curl
+tcp6      [::]:22         [::]:0             LISTEN       7    sshd
+`},
+		{[]string{"-json"}, `[
+  {
+    "Proto": "tcp4",
+    "Local": "10.0.0.5:51000",
+    "Remote": "93.184.216.34:443",
+    "State": "ESTABLISHED",
+    "PID": 100,
+    "Process": "curl",
+    "Listening": false
+  },
+  {
+    "Proto": "tcp6",
+    "Local": "[::]:22",
+    "Remote": "[::]:0",
+    "State": "LISTEN",
+    "PID": 7,
+    "Process": "sshd",
+    "Listening": true
+  }
+]
+`},
+		{[]string{"-json", "-json-compact"}, `[{"Proto":"tcp4","Local":"10.0.0.5:51000","Remote":"93.184.216.34:443","State":"ESTABLISHED","PID":100,"Process":"curl","Listening":false},{"Proto":"tcp6","Local":"[::]:22","Remote":"[::]:0","State":"LISTEN","PID":7,"Process":"sshd","Listening":true}]
+`},
+		{[]string{"-jsonl"}, `{"updated":"2026-01-02T03:04:05Z","title":"Live TCP connections","rows":[{"Proto":"tcp4","Local":"10.0.0.5:51000","Remote":"93.184.216.34:443","State":"ESTABLISHED","PID":100,"Process":"curl","Listening":false},{"Proto":"tcp6","Local":"[::]:22","Remote":"[::]:0","State":"LISTEN","PID":7,"Process":"sshd","Listening":true}]}
+`},
+		{[]string{"-csv"}, `updated,proto,local,remote,state,pid,process
+2026-01-02T03:04:05Z,tcp4,10.0.0.5:51000,93.184.216.34:443,ESTABLISHED,100,curl
+2026-01-02T03:04:05Z,tcp6,[::]:22,[::]:0,LISTEN,7,sshd
+`},
+		{[]string{"-format", "tsv"}, `updated	proto	local	remote	state	pid	process
+2026-01-02T03:04:05Z	tcp4	10.0.0.5:51000	93.184.216.34:443	ESTABLISHED	100	curl
+2026-01-02T03:04:05Z	tcp6	[::]:22	[::]:0	LISTEN	7	sshd
+`},
+		{[]string{"-influx"}, `tcp_connections,state=ESTABLISHED,proto=tcp4,process=curl count=1i 1767323045000000000
+tcp_connections,state=LISTEN,proto=tcp6,process=sshd count=1i 1767323045000000000
+`},
+		{[]string{"-format", "prometheus"}, `# HELP tcpwatch_connections TCP connections by state and protocol.
+# TYPE tcpwatch_connections gauge
+tcpwatch_connections{state="ESTABLISHED",proto="tcp4"} 1
+tcpwatch_connections{state="LISTEN",proto="tcp6"} 1
+# HELP tcpwatch_last_refresh_timestamp_seconds Time of the last successful refresh.
+# TYPE tcpwatch_last_refresh_timestamp_seconds gauge
+tcpwatch_last_refresh_timestamp_seconds 1.767323045e+09
+`},
+	}
+	for _, tt := range tests {
+		opts, err := parseFlags(tt.args)
+		if err != nil {
+			t.Fatalf("parseFlags(%q): %v", tt.args, err)
+		}
+		var b bytes.Buffer
+		if err := newRenderer(opts, &watchState{}).render(&b, renderSnap()); err != nil {
+			t.Fatalf("%q: render: %v", tt.args, err)
+		}
+		if b.String() != tt.want {
+			t.Errorf("%q: got\n%s\nwant\n%s", tt.args, b.String(), tt.want)
+		}
+	}
+}
+
+// The csv and table renderers keep state: the CSV header and, with
+// -no-header-repeat, the table header are printed on the first refresh only.
+func TestRenderersHeaderOnce(t *testing.T) {
+	for _, args := range [][]string{{"-csv"}, {"-no-clear", "-no-header-repeat"}} {
+		opts, err := parseFlags(args)
+		if err != nil {
+			t.Fatalf("parseFlags(%q): %v", args, err)
+		}
+		r := newRenderer(opts, &watchState{})
+		var first, second bytes.Buffer
+		if err := r.render(&first, renderSnap()); err != nil {
+			t.Fatal(err)
+		}
+		if err := r.render(&second, renderSnap()); err != nil {
+			t.Fatal(err)
+		}
+		if !strings.Contains(strings.ToUpper(first.String()), "PROTO") {
+			t.Errorf("%q: first refresh has no header:\n%s", args, first.String())
+		}
+		if strings.Contains(strings.ToUpper(second.String()), "PROTO") {
+			t.Errorf("%q: second refresh repeats the header:\n%s", args, second.String())
+		}
+	}
+}
+
+// -grep filters rows before rendering, so the -sectioned section titles and
+// the -tree process lines are kept for the rows that match.
+func TestTableRendererGrep(t *testing.T) {
+	snap := renderSnap()
+	snap.Rows[0].Dir, snap.Rows[1].Dir = "out", "listen"
+	tests := []struct {
+		args []string
+		want string
+	}{
+		{[]string{"-no-clear", "-grep", "LISTEN"}, `tcp6  [::]:22  [::]:0  LISTEN  7  sshd
+`},
+		{[]string{"-no-clear", "-grep", "curl", "-grep-keep-header"}, `Live TCP connections
+Updated:  2026-01-02T03:04:05Z
+PROTO     LOCAL           REMOTE             STATE        PID  PROCESS
+tcp4      10.0.0.5:51000  93.184.216.34:443  ESTABLISHED  100  curl
+`},
+		{[]string{"-no-clear", "-sectioned", "-grep", "sshd"}, `
+INBOUND (1)
+tcp6  listen  [::]:22  [::]:0  LISTEN  7  sshd
+
+OUTBOUND (0)
+`},
+		{[]string{"-no-clear", "-tree", "-grep", "443"}, `curl (pid 100)  tcp4  10.0.0.5:51000  93.184.216.34:443  ESTABLISHED
+`},
+	}
+	for _, tt := range tests {
+		opts, err := parseFlags(tt.args)
+		if err != nil {
+			t.Fatalf("parseFlags(%q): %v", tt.args, err)
+		}
+		var b bytes.Buffer
+		if err := newRenderer(opts, &watchState{}).render(&b, snap); err != nil {
+			t.Fatalf("%q: render: %v", tt.args, err)
+		}
+		if b.String() != tt.want {
+			t.Errorf("%q: got\n%q\nwant\n%q", tt.args, b.String(), tt.want)
+		}
+	}
+}
+
+// Rows marked only by -per-process-limit don't count as -warn-port rows.
+func TestWarnPortCount(t *testing.T) {
+	opts, err := parseFlags([]string{"-no-clear", "-warn-port", "22", "-per-process-limit", "50"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	snap := renderSnap()
+	snap.Rows[0].Conns, snap.Rows[0].FDLimit, snap.Rows[0].Warn = 900, 1024, true
+	snap.Rows[1].Warn = true
+	var b bytes.Buffer
+	if err := printRows(&b, opts, &watchState{}, snap.Rows); err != nil {
+		t.Fatal(err)
+	}
+	if want := "\n1 connection(s) on a -warn-port port\n"; !strings.HasSuffix(b.String(), want) {
+		t.Errorf("got\n%s\nwant it to end with %q", b.String(), want)
+	}
+}
+
+// The -warn-port count covers every row, not just the -sample shown.
+func TestWarnPortCountBeforeSample(t *testing.T) {
+	opts, err := parseFlags([]string{"-no-clear", "-warn-port", "22", "-sample", "0.01"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	var rows []render.Row
+	for i := range 50 {
+		rows = append(rows, render.Row{Proto: "tcp4", Local: "10.0.0.5:22", Remote: fmt.Sprintf("192.0.2.%d:40000", i+1), State: "ESTABLISHED"})
+	}
+	var b bytes.Buffer
+	if err := printRows(&b, opts, &watchState{}, rows); err != nil {
+		t.Fatal(err)
+	}
+	if want := "\n50 connection(s) on a -warn-port port\n"; !strings.HasSuffix(b.String(), want) {
+		t.Errorf("got\n%s\nwant it to end with %q", b.String(), want)
+	}
+}
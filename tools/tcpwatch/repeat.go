@@ -0,0 +1,58 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"io"
+	"slices"
+	"time"
+)
+
+// jsonRepeat stands in for snapshots that -merge-repeats suppressed: the
+// previous snapshot's rows were seen Repeat more times, the last at Updated.
+type jsonRepeat struct {
+	Repeat  int       `json:"repeat"`
+	Updated time.Time `json:"updated"`
+}
+
+// writeJSONLSnapshot writes snap as one NDJSON line. With -merge-repeats a
+// snapshot whose rows equal the previous one's is only counted; the count is
+// written as a jsonRepeat record before the next differing snapshot (or on
+// exit, see flushRepeats).
+func writeJSONLSnapshot(w io.Writer, opts options, st *watchState, snap jsonSnapshot) error {
+	enc := json.NewEncoder(w)
+	if !opts.mergeRepeats {
+		return enc.Encode(snap)
+	}
+
+	// Rows are sorted before printing, so equal sets encode identically.
+	// -age grows on every refresh, so it and -seq are left out.
+	rows := slices.Clone(snap.Rows)
+	for i := range rows {
+		rows[i].Age, rows[i].Seq = 0, 0
+	}
+	key, err := json.Marshal(rows)
+	if err != nil {
+		return err
+	}
+	if st.lastRows != nil && bytes.Equal(key, st.lastRows) {
+		st.repeats++
+		st.repeatUpdated = snap.Updated
+		return nil
+	}
+	if err := st.flushRepeats(w); err != nil {
+		return err
+	}
+	st.lastRows = key
+	return enc.Encode(snap)
+}
+
+// flushRepeats writes the pending -merge-repeats count, if any.
+func (st *watchState) flushRepeats(w io.Writer) error {
+	if st.repeats == 0 {
+		return nil
+	}
+	err := json.NewEncoder(w).Encode(jsonRepeat{Repeat: st.repeats, Updated: st.repeatUpdated})
+	st.repeats = 0
+	return err
+}
@@ -0,0 +1,32 @@
+package main
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/bulent/morzer/tools/tcpwatch/internal/render"
+)
+
+// Snapshots that differ only in -age and -seq are still merged.
+func TestMergeRepeatsIgnoresAge(t *testing.T) {
+	opts := options{jsonLines: true, mergeRepeats: true}
+	st := &watchState{}
+	var out bytes.Buffer
+	now := time.Now()
+	for i := range 3 {
+		row := render.Row{Proto: "tcp4", Local: "10.0.0.5:40000", Remote: "192.0.2.1:443", State: "ESTABLISHED", Age: time.Duration(i+1) * time.Second, Seq: 1}
+		snap := jsonSnapshot{Updated: now.Add(time.Duration(i) * time.Second), Rows: []render.Row{row}}
+		if err := writeJSONLSnapshot(&out, opts, st, snap); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := st.flushRepeats(&out); err != nil {
+		t.Fatal(err)
+	}
+	lines := strings.Split(strings.TrimSpace(out.String()), "\n")
+	if len(lines) != 2 || !strings.HasPrefix(lines[1], `{"repeat":2,`) {
+		t.Errorf("got %d lines %q, want a snapshot and a repeat of 2", len(lines), lines)
+	}
+}
@@ -0,0 +1,115 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"syscall"
+
+	"github.com/bulent/morzer/tools/tcpwatch/internal/render"
+	gnet "github.com/shirou/gopsutil/v4/net"
+)
+
+// errReplayDone is returned once a -replay recording without -replay-loop
+// has been played to the end; the watch loop then exits normally.
+var errReplayDone = errors.New("replay finished")
+
+// replaySource is the -replay connection source. A recording is a JSON
+// array of snapshots, each an array of rows as printed by -json:
+//
+//	[
+//	  [{"Proto":"tcp4","Local":"10.0.0.5:51234","Remote":"93.184.216.34:443","State":"ESTABLISHED","PID":812,"Process":"curl"}],
+//	  []
+//	]
+//
+// Each refresh takes the next snapshot. Process names come from the
+// recording, never from this machine.
+type replaySource struct {
+	snaps [][]render.Row
+	loop  bool
+	next  int
+	names procNames
+}
+
+func loadReplay(path string, loop bool) (*replaySource, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("-replay: %w", err)
+	}
+	var snaps [][]render.Row
+	err = json.Unmarshal(data, &snaps)
+	if err != nil {
+		// A -record file whose writer didn't shut down cleanly lacks the
+		// closing bracket.
+		if json.Unmarshal(append(bytes.TrimSpace(data), ']'), &snaps) == nil {
+			err = nil
+		}
+	}
+	if err != nil {
+		return nil, fmt.Errorf("-replay: %s: want an array of -json row arrays: %w", path, err)
+	}
+	if len(snaps) == 0 {
+		return nil, fmt.Errorf("-replay: %s has no snapshots", path)
+	}
+	return &replaySource{snaps: snaps, loop: loop}, nil
+}
+
+func (r *replaySource) Connections(ctx context.Context, kind string) ([]gnet.ConnectionStat, error) {
+	if r.next == len(r.snaps) {
+		if !r.loop {
+			return nil, errReplayDone
+		}
+		r.next = 0
+	}
+	rows := r.snaps[r.next]
+	r.next++
+
+	conns := make([]gnet.ConnectionStat, 0, len(rows))
+	r.names = make(procNames)
+	for _, row := range rows {
+		c := gnet.ConnectionStat{
+			Family: replayFamily(row),
+			Type:   syscall.SOCK_STREAM,
+			Status: row.State,
+			Pid:    row.PID,
+		}
+		if c.Family == syscall.AF_UNIX {
+			c.Laddr.IP, c.Raddr.IP = replayPath(row.Local), replayPath(row.Remote)
+		} else {
+			c.Laddr, _ = splitHostPort(row.Local, ':')
+			c.Raddr, _ = splitHostPort(row.Remote, ':')
+		}
+		conns = append(conns, c)
+		r.names[row.PID] = row.Process
+	}
+	return conns, nil
+}
+
+// procNames returns the process names of the snapshot Connections last
+// returned.
+func (r *replaySource) procNames() procNames {
+	return r.names
+}
+
+func replayFamily(row render.Row) uint32 {
+	switch row.Proto {
+	case "tcp4":
+		return syscall.AF_INET
+	case "tcp6":
+		return syscall.AF_INET6
+	case "unix":
+		return syscall.AF_UNIX
+	}
+	host, _ := splitHostPort(row.Local, ':')
+	return addrFamily(host.IP)
+}
+
+func replayPath(s string) string {
+	if s == "-" {
+		return ""
+	}
+	return s
+}
@@ -0,0 +1,24 @@
+package main
+
+import (
+	"fmt"
+	"hash/fnv"
+	"math"
+
+	"github.com/bulent/morzer/tools/tcpwatch/internal/render"
+)
+
+// sampleRows keeps about rate (0 < rate <= 1) of rows for -sample. Rows are
+// picked by a hash of their identity, so a connection that is shown stays
+// shown on later refreshes. rows itself is left unchanged.
+func sampleRows(rows []render.Row, rate float64, id identity) []render.Row {
+	out := []render.Row{}
+	for _, r := range rows {
+		h := fnv.New64a()
+		fmt.Fprintf(h, "%v", id.key(r))
+		if float64(h.Sum64())/math.MaxUint64 < rate {
+			out = append(out, r)
+		}
+	}
+	return out
+}
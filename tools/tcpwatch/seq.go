@@ -0,0 +1,25 @@
+package main
+
+import "github.com/bulent/morzer/tools/tcpwatch/internal/render"
+
+// setSeqs fills in each row's Seq: a number handed out in the order
+// tcpwatch first saw the connections (-seq), kept for as long as the
+// connection is listed. Numbers are never reused, so a connection that
+// disappears and comes back gets a new one. Rows with the same identity
+// share their number.
+func (st *watchState) setSeqs(rows []render.Row, id identity) {
+	seen := make(map[connKey]int, len(rows))
+	for i := range rows {
+		k := id.key(rows[i])
+		seq, ok := st.seqs[k]
+		if !ok {
+			if seq, ok = seen[k]; !ok {
+				st.lastSeq++
+				seq = st.lastSeq
+			}
+		}
+		seen[k] = seq
+		rows[i].Seq = seq
+	}
+	st.seqs = seen
+}
@@ -0,0 +1,45 @@
+package main
+
+import (
+	"slices"
+	"testing"
+
+	"github.com/bulent/morzer/tools/tcpwatch/internal/render"
+)
+
+func TestSetSeqs(t *testing.T) {
+	id, err := parseIdentity("local,remote")
+	if err != nil {
+		t.Fatal(err)
+	}
+	conn := func(local, remote string) render.Row {
+		return render.Row{Proto: "tcp4", Local: local, Remote: remote, State: "ESTABLISHED"}
+	}
+	a := conn("10.0.0.5:40000", "192.0.2.1:443")
+	b := conn("10.0.0.5:40001", "192.0.2.1:443")
+	c := conn("10.0.0.5:40002", "192.0.2.1:443")
+	aDup := a // same identity as a, e.g. a second socket of another process
+	aDup.PID = 99
+
+	st := &watchState{}
+	for _, tt := range []struct {
+		name string
+		rows []render.Row
+		want []int
+	}{
+		{"first refresh", []render.Row{a, b, aDup}, []int{1, 2, 1}},
+		{"stable, in any order", []render.Row{b, a}, []int{2, 1}},
+		{"new connection", []render.Row{c, a, b}, []int{3, 1, 2}},
+		{"b disappears", []render.Row{a, c}, []int{1, 3}},
+		{"b comes back with a new number", []render.Row{b, a, c}, []int{4, 1, 3}},
+	} {
+		st.setSeqs(tt.rows, id)
+		var got []int
+		for _, r := range tt.rows {
+			got = append(got, r.Seq)
+		}
+		if !slices.Equal(got, tt.want) {
+			t.Errorf("%s: seqs %v, want %v", tt.name, got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,78 @@
+package main
+
+// wellKnownServices maps common TCP ports to service names. It is built in
+// rather than read from /etc/services so labels are the same on every
+// platform and cover popular unregistered ports (e.g. 6379 redis).
+var wellKnownServices = map[uint32]string{
+	20:    "ftp-data",
+	21:    "ftp",
+	22:    "ssh",
+	23:    "telnet",
+	25:    "smtp",
+	53:    "dns",
+	80:    "http",
+	88:    "kerberos",
+	110:   "pop3",
+	111:   "rpcbind",
+	123:   "ntp",
+	135:   "msrpc",
+	139:   "netbios",
+	143:   "imap",
+	389:   "ldap",
+	443:   "https",
+	445:   "smb",
+	465:   "smtps",
+	548:   "afp",
+	587:   "submission",
+	631:   "ipp",
+	636:   "ldaps",
+	853:   "dns-tls",
+	993:   "imaps",
+	995:   "pop3s",
+	1433:  "mssql",
+	1521:  "oracle",
+	1883:  "mqtt",
+	2049:  "nfs",
+	2181:  "zookeeper",
+	2375:  "docker",
+	2376:  "docker-tls",
+	3000:  "http-dev",
+	3306:  "mysql",
+	3389:  "rdp",
+	4222:  "nats",
+	5000:  "upnp",
+	5222:  "xmpp",
+	5223:  "apns",
+	5353:  "mdns",
+	5432:  "postgres",
+	5672:  "amqp",
+	5900:  "vnc",
+	6379:  "redis",
+	6443:  "kube-api",
+	8080:  "http-alt",
+	8443:  "https-alt",
+	9000:  "http-alt",
+	9090:  "prometheus",
+	9092:  "kafka",
+	9100:  "node-exporter",
+	9200:  "elasticsearch",
+	11211: "memcached",
+	27017: "mongodb",
+}
+
+// serviceName returns the well-known service for a port, or "".
+func serviceName(port uint32) string {
+	return wellKnownServices[port]
+}
+
+// connService labels a connection with the service on the serving side: the
+// local port for listeners and inbound connections, the remote port for
+// outbound ones.
+func connService(localPort, remotePort uint32, dir string) string {
+	switch dir {
+	case "listen", "in":
+		return serviceName(localPort)
+	default:
+		return serviceName(remotePort)
+	}
+}
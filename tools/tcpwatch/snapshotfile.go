@@ -0,0 +1,46 @@
+package main
+
+import (
+	"encoding/json"
+	"os"
+	"path/filepath"
+)
+
+// writeSnapshotFile replaces path with snap atomically: it writes a temp file
+// in the same directory and renames it over path, so readers polling the file
+// never see a partial snapshot.
+func writeSnapshotFile(path string, snap jsonSnapshot) error {
+	f, err := os.CreateTemp(filepath.Dir(path), "."+filepath.Base(path)+".tmp-*")
+	if err != nil {
+		return err
+	}
+	tmp := f.Name()
+	// CreateTemp uses 0600; snapshots are meant for other readers.
+	_ = f.Chmod(0o644)
+
+	enc := json.NewEncoder(f)
+	enc.SetIndent("", "  ")
+	if err := enc.Encode(snap); err != nil {
+		f.Close()
+		os.Remove(tmp)
+		return err
+	}
+	if err := f.Close(); err != nil {
+		os.Remove(tmp)
+		return err
+	}
+	if err := os.Rename(tmp, path); err != nil {
+		os.Remove(tmp)
+		return err
+	}
+	return nil
+}
+
+// dumpSnapshot writes snap to a new file in dir named after its time, e.g.
+// tcpwatch-20240102T150405.123456789.json, and returns the file's path. The
+// name has nanoseconds so snapshots taken within one second don't overwrite
+// each other's dumps.
+func dumpSnapshot(dir string, snap jsonSnapshot) (string, error) {
+	path := filepath.Join(dir, "tcpwatch-"+snap.Updated.Format("20060102T150405.000000000")+".json")
+	return path, writeSnapshotFile(path, snap)
+}
@@ -0,0 +1,93 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"slices"
+	"testing"
+	"time"
+)
+
+// The -snapshot-file rows are in the same order as the -json output.
+func TestSnapshotFileOrder(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "snap.json")
+	opts, err := parseFlags([]string{"-once", "-json", "-snapshot-file", path})
+	if err != nil {
+		t.Fatal(err)
+	}
+	opts.source = testSource
+	var out bytes.Buffer
+	if _, err := runOnce(context.Background(), &out, opts, newProcResolver(time.Minute, opts.procCacheSize, opts.resolveMethod), &watchState{}); err != nil {
+		t.Fatal(err)
+	}
+
+	var printed []struct{ Local, Remote string }
+	if err := json.Unmarshal(out.Bytes(), &printed); err != nil {
+		t.Fatal(err)
+	}
+	b, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var snap struct {
+		Rows []struct{ Local, Remote string }
+	}
+	if err := json.Unmarshal(b, &snap); err != nil {
+		t.Fatal(err)
+	}
+	if len(printed) != len(testSource.conns)-1 || !slices.Equal(snap.Rows, printed) {
+		t.Errorf("snapshot rows %v, want the -json order %v", snap.Rows, printed)
+	}
+}
+
+// -wait writes -snapshot-file like a one-shot run.
+func TestWaitWritesSnapshotFile(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "snap.json")
+	opts, err := parseFlags([]string{"-once", "-wait", "1s", "-snapshot-file", path})
+	if err != nil {
+		t.Fatal(err)
+	}
+	opts.source = testSource
+	var out bytes.Buffer
+	found, err := waitForRows(context.Background(), &out, opts, newProcResolver(time.Minute, opts.procCacheSize, opts.resolveMethod), &watchState{}, opts.wait)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !found {
+		t.Fatal("waitForRows found no rows")
+	}
+	b, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var snap struct{ Rows []struct{ Local string } }
+	if err := json.Unmarshal(b, &snap); err != nil {
+		t.Fatal(err)
+	}
+	if len(snap.Rows) != len(testSource.conns)-1 {
+		t.Errorf("snapshot has %d rows, want %d", len(snap.Rows), len(testSource.conns)-1)
+	}
+}
+
+// Snapshots from the same second are dumped to separate files.
+func TestDumpSnapshotNames(t *testing.T) {
+	dir := t.TempDir()
+	at := time.Date(2026, 1, 2, 3, 4, 5, 0, time.UTC)
+	a, err := dumpSnapshot(dir, jsonSnapshot{Updated: at})
+	if err != nil {
+		t.Fatal(err)
+	}
+	b, err := dumpSnapshot(dir, jsonSnapshot{Updated: at.Add(500 * time.Millisecond)})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if a == b {
+		t.Fatalf("both snapshots dumped to %s", a)
+	}
+	if want := filepath.Join(dir, "tcpwatch-20260102T030405.500000000.json"); b != want {
+		t.Errorf("dumped to %s, want %s", b, want)
+	}
+}
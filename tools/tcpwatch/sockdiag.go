@@ -0,0 +1,36 @@
+package main
+
+import (
+	"net/netip"
+
+	gnet "github.com/shirou/gopsutil/v4/net"
+
+	"github.com/bulent/morzer/tools/tcpwatch/internal/render"
+)
+
+// sockKey identifies a socket by its endpoints for matching sock_diag
+// results to connections. Addresses are unmapped and unzoned so IPv4-mapped
+// IPv6 sockets match however each source prints them.
+type sockKey struct {
+	local, remote netip.AddrPort
+}
+
+// sockInfo is what -sock-diag and -counters add to a connection. counters is
+// nil unless asked for and reported by the kernel.
+type sockInfo struct {
+	inode    uint32
+	uid      uint32
+	counters *render.Counters
+}
+
+func connSockKey(c gnet.ConnectionStat) sockKey {
+	return sockKey{local: addrPortOf(c.Laddr), remote: addrPortOf(c.Raddr)}
+}
+
+func addrPortOf(a gnet.Addr) netip.AddrPort {
+	ip, err := netip.ParseAddr(a.IP)
+	if err != nil {
+		ip = netip.IPv4Unspecified()
+	}
+	return netip.AddrPortFrom(ip.WithZone("").Unmap(), uint16(a.Port))
+}
@@ -0,0 +1,144 @@
+//go:build linux
+
+package main
+
+import (
+	"encoding/binary"
+	"fmt"
+	"net/netip"
+	"syscall"
+
+	"github.com/bulent/morzer/tools/tcpwatch/internal/render"
+)
+
+const sockDiagSupported = true
+
+// Netlink sock_diag constants from linux/sock_diag.h and linux/inet_diag.h.
+const (
+	netlinkSockDiag   = 4  // NETLINK_SOCK_DIAG
+	sockDiagByFamily  = 20 // SOCK_DIAG_BY_FAMILY
+	inetDiagReqV2Size = 56 // sizeof(struct inet_diag_req_v2)
+	inetDiagMsgSize   = 72 // sizeof(struct inet_diag_msg)
+	inetDiagInfo      = 2  // INET_DIAG_INFO, a struct tcp_info attribute
+	// tcpInfoCountersEnd is the offset just past tcpi_segs_in in struct
+	// tcp_info (linux/tcp.h); older kernels send a shorter struct.
+	tcpInfoCountersEnd = 144
+)
+
+// sockDiag dumps all TCP sockets through NETLINK_SOCK_DIAG and returns their
+// inode and owning UID keyed by endpoints, and with counters also their
+// byte and segment counts from tcp_info. Unlike /proc/net/tcp this is a
+// single structured query per address family.
+func sockDiag(counters bool) (map[sockKey]sockInfo, error) {
+	fd, err := syscall.Socket(syscall.AF_NETLINK, syscall.SOCK_DGRAM|syscall.SOCK_CLOEXEC, netlinkSockDiag)
+	if err != nil {
+		return nil, fmt.Errorf("sock_diag: %w", err)
+	}
+	defer syscall.Close(fd)
+
+	socks := make(map[sockKey]sockInfo)
+	for _, family := range []uint8{syscall.AF_INET, syscall.AF_INET6} {
+		if err := sockDiagDump(fd, family, counters, socks); err != nil {
+			return nil, fmt.Errorf("sock_diag: %w", err)
+		}
+	}
+	return socks, nil
+}
+
+func sockDiagDump(fd int, family uint8, counters bool, socks map[sockKey]sockInfo) error {
+	req := make([]byte, syscall.NLMSG_HDRLEN+inetDiagReqV2Size)
+	ne := binary.NativeEndian
+	ne.PutUint32(req[0:4], uint32(len(req)))
+	ne.PutUint16(req[4:6], sockDiagByFamily)
+	ne.PutUint16(req[6:8], syscall.NLM_F_REQUEST|syscall.NLM_F_DUMP)
+	ne.PutUint32(req[8:12], 1) // sequence number
+	body := req[syscall.NLMSG_HDRLEN:]
+	body[0] = family
+	body[1] = syscall.IPPROTO_TCP
+	if counters {
+		body[2] = 1 << (inetDiagInfo - 1) // idiag_ext
+	}
+	ne.PutUint32(body[4:8], 0xffffffff) // all states
+
+	if err := syscall.Sendto(fd, req, 0, &syscall.SockaddrNetlink{Family: syscall.AF_NETLINK}); err != nil {
+		return err
+	}
+
+	buf := make([]byte, 64<<10)
+	for {
+		n, _, err := syscall.Recvfrom(fd, buf, 0)
+		if err != nil {
+			return err
+		}
+		msgs, err := syscall.ParseNetlinkMessage(buf[:n])
+		if err != nil {
+			return err
+		}
+		for _, m := range msgs {
+			switch m.Header.Type {
+			case syscall.NLMSG_DONE:
+				return nil
+			case syscall.NLMSG_ERROR:
+				if len(m.Data) >= 4 {
+					if errno := int32(ne.Uint32(m.Data[:4])); errno != 0 {
+						return syscall.Errno(-errno)
+					}
+				}
+				return nil
+			}
+			if len(m.Data) < inetDiagMsgSize {
+				continue
+			}
+			k, info := parseInetDiagMsg(m.Data)
+			socks[k] = info
+		}
+	}
+}
+
+// parseInetDiagMsg decodes a struct inet_diag_msg and the attributes after
+// it. Ports and addresses in inet_diag_sockid are in network byte order; the
+// rest is native.
+func parseInetDiagMsg(d []byte) (sockKey, sockInfo) {
+	family := d[0]
+	sport := binary.BigEndian.Uint16(d[4:6])
+	dport := binary.BigEndian.Uint16(d[6:8])
+	src, dst := diagAddr(family, d[8:24]), diagAddr(family, d[24:40])
+	k := sockKey{local: netip.AddrPortFrom(src, sport), remote: netip.AddrPortFrom(dst, dport)}
+	info := sockInfo{
+		uid:   binary.NativeEndian.Uint32(d[64:68]),
+		inode: binary.NativeEndian.Uint32(d[68:72]),
+	}
+	for attrs := d[inetDiagMsgSize:]; len(attrs) >= syscall.SizeofRtAttr; {
+		size := int(binary.NativeEndian.Uint16(attrs[0:2]))
+		if size < syscall.SizeofRtAttr || size > len(attrs) {
+			break
+		}
+		if binary.NativeEndian.Uint16(attrs[2:4]) == inetDiagInfo {
+			info.counters = parseTCPInfoCounters(attrs[syscall.SizeofRtAttr:size])
+		}
+		attrs = attrs[min(len(attrs), (size+syscall.RTA_ALIGNTO-1)&^(syscall.RTA_ALIGNTO-1)):]
+	}
+	return k, info
+}
+
+// parseTCPInfoCounters reads tcpi_bytes_acked, tcpi_bytes_received,
+// tcpi_segs_out and tcpi_segs_in from a struct tcp_info.
+func parseTCPInfoCounters(ti []byte) *render.Counters {
+	if len(ti) < tcpInfoCountersEnd {
+		return nil
+	}
+	ne := binary.NativeEndian
+	return &render.Counters{
+		BytesOut: ne.Uint64(ti[120:128]),
+		BytesIn:  ne.Uint64(ti[128:136]),
+		PktsOut:  uint64(ne.Uint32(ti[136:140])),
+		PktsIn:   uint64(ne.Uint32(ti[140:144])),
+	}
+}
+
+func diagAddr(family uint8, b []byte) netip.Addr {
+	if family == syscall.AF_INET {
+		return netip.AddrFrom4([4]byte(b[:4]))
+	}
+	return netip.AddrFrom16([16]byte(b[:16])).Unmap()
+}
@@ -0,0 +1,35 @@
+package main
+
+import (
+	"encoding/binary"
+	"testing"
+
+	"github.com/bulent/morzer/tools/tcpwatch/internal/render"
+)
+
+func TestParseTCPInfoCounters(t *testing.T) {
+	// A struct tcp_info as the kernel sends it, with the counters at their
+	// fixed offsets and recognizable values everywhere else.
+	ti := make([]byte, 232)
+	for i := range ti {
+		ti[i] = 0xee
+	}
+	ne := binary.NativeEndian
+	ne.PutUint64(ti[120:], 1<<33+5) // tcpi_bytes_acked
+	ne.PutUint64(ti[128:], 4096)    // tcpi_bytes_received
+	ne.PutUint32(ti[136:], 70)      // tcpi_segs_out
+	ne.PutUint32(ti[140:], 12)      // tcpi_segs_in
+
+	want := render.Counters{BytesOut: 1<<33 + 5, BytesIn: 4096, PktsOut: 70, PktsIn: 12}
+	got := parseTCPInfoCounters(ti)
+	if got == nil || *got != want {
+		t.Errorf("parseTCPInfoCounters = %+v, want %+v", got, want)
+	}
+	// Older kernels send a shorter struct without the counters.
+	if got := parseTCPInfoCounters(ti[:tcpInfoCountersEnd-1]); got != nil {
+		t.Errorf("short tcp_info: got %+v, want nil", got)
+	}
+	if got := parseTCPInfoCounters(ti[:tcpInfoCountersEnd]); got == nil || *got != want {
+		t.Errorf("tcp_info ending at the counters: got %+v, want %+v", got, want)
+	}
+}
@@ -0,0 +1,15 @@
+//go:build !linux
+
+package main
+
+import (
+	"fmt"
+	"runtime"
+)
+
+// NETLINK_SOCK_DIAG is Linux-only.
+const sockDiagSupported = false
+
+func sockDiag(counters bool) (map[sockKey]sockInfo, error) {
+	return nil, fmt.Errorf("sock_diag not available on %s", runtime.GOOS)
+}
@@ -0,0 +1,55 @@
+package main
+
+import (
+	"bufio"
+	"context"
+	"fmt"
+	"os/exec"
+	"strings"
+
+	gnet "github.com/shirou/gopsutil/v4/net"
+)
+
+// sshScript runs on the remote host. It prints a marker line naming the tool
+// it used, followed by that tool's output, so the matching parser can be
+// picked locally. It assumes a POSIX shell with ss or netstat on the remote
+// side (Linux or macOS).
+const sshScript = `if command -v ss >/dev/null 2>&1; then echo '#ss'; ss -H -tanp; ` +
+	`elif [ "$(uname -s)" = Darwin ]; then echo '#netstat-darwin'; netstat -anv -p tcp; ` +
+	`else echo '#netstat-linux'; netstat -tanp 2>/dev/null; fi`
+
+// sshConnections lists the connections of a remote host by running ss or
+// netstat over ssh. Authentication is left to ssh itself (agent, keys,
+// ~/.ssh/config); BatchMode keeps it from prompting for a password. Process
+// names come from the remote tool's output and PIDs refer to the remote host.
+// "--" keeps target from being read as an ssh option.
+func sshConnections(ctx context.Context, target string) ([]gnet.ConnectionStat, procNames, error) {
+	cmd := exec.CommandContext(ctx, "ssh", "-o", "BatchMode=yes", "-o", "ConnectTimeout=10", "--", target, sshScript)
+	out, err := cmd.Output()
+	if err != nil {
+		if ee, ok := err.(*exec.ExitError); ok && len(ee.Stderr) > 0 {
+			return nil, nil, fmt.Errorf("ssh %s: %s", target, strings.TrimSpace(string(ee.Stderr)))
+		}
+		return nil, nil, fmt.Errorf("ssh %s: %w", target, err)
+	}
+
+	marker, body, _ := strings.Cut(string(out), "\n")
+	var conns []gnet.ConnectionStat
+	var names procNames
+	switch strings.TrimSpace(marker) {
+	case "#ss":
+		conns, names = parseSS(body)
+	case "#netstat-darwin":
+		conns, names = parseNetstatDarwin(body)
+	case "#netstat-linux":
+		conns, names = parseNetstatLinux(body)
+	default:
+		sc := bufio.NewScanner(strings.NewReader(string(out)))
+		sc.Scan()
+		return nil, nil, fmt.Errorf("ssh %s: unexpected output %q", target, sc.Text())
+	}
+	if names == nil {
+		names = make(procNames)
+	}
+	return conns, names, nil
+}
@@ -0,0 +1,37 @@
+package main
+
+import (
+	"time"
+
+	"github.com/bulent/morzer/tools/tcpwatch/internal/render"
+)
+
+// stateEntry records when a connection entered its current state.
+type stateEntry struct {
+	state string
+	since time.Time
+}
+
+// stuckRows keeps the rows that have been in one of the -stuck-state states
+// for at least -stuck, filling in their InState. Like -age, it can only
+// count from the first refresh that saw the connection in that state.
+func (st *watchState) stuckRows(rows []render.Row, opts options, now time.Time) []render.Row {
+	seen := make(map[connKey]stateEntry, len(rows))
+	out := rows[:0]
+	for _, r := range rows {
+		k := opts.identity.key(r)
+		e, ok := st.stateSince[k]
+		if !ok || e.state != r.State {
+			e = stateEntry{state: r.State, since: now}
+		}
+		seen[k] = e
+		if _, watched := opts.stuckStates[r.State]; !watched {
+			continue
+		}
+		if r.InState = now.Sub(e.since); r.InState >= opts.stuck {
+			out = append(out, r)
+		}
+	}
+	st.stateSince = seen
+	return out
+}
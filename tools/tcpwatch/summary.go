@@ -0,0 +1,39 @@
+package main
+
+import (
+	"encoding/json"
+	"io"
+	"time"
+
+	"github.com/bulent/morzer/tools/tcpwatch/internal/render"
+)
+
+// jsonSummary is interleaved into a -jsonl stream by -summary-every so
+// consumers get rollups without aggregating snapshots themselves. Kind tells
+// it apart from snapshot objects.
+type jsonSummary struct {
+	Kind    string         `json:"kind"`
+	Updated time.Time      `json:"updated"`
+	Total   int            `json:"total"`
+	ByState map[string]int `json:"by_state"`
+	ByProto map[string]int `json:"by_proto"`
+}
+
+func summarize(rows []render.Row, now time.Time) jsonSummary {
+	s := jsonSummary{
+		Kind:    "summary",
+		Updated: now,
+		Total:   len(rows),
+		ByState: make(map[string]int),
+		ByProto: make(map[string]int),
+	}
+	for _, r := range rows {
+		s.ByState[r.State]++
+		s.ByProto[r.Proto]++
+	}
+	return s
+}
+
+func writeSummary(w io.Writer, rows []render.Row, now time.Time) error {
+	return json.NewEncoder(w).Encode(summarize(rows, now))
+}
@@ -0,0 +1,67 @@
+package main
+
+import (
+	"fmt"
+	"net"
+	"os"
+	"strings"
+	"time"
+)
+
+// syslogSink sends -events to syslog as RFC 5424 messages. It writes the
+// format itself over a plain connection rather than using log/syslog, which
+// only speaks the older BSD format and doesn't exist on Windows.
+type syslogSink struct {
+	conn     net.Conn
+	stream   bool
+	hostname string
+	pid      int
+}
+
+// newSyslogSink dials addr ("host:port", "udp://host:port" or
+// "tcp://host:port"), or the local syslog socket when addr is empty.
+func newSyslogSink(addr string) (*syslogSink, error) {
+	var conn net.Conn
+	var err error
+	stream := false
+	switch {
+	case addr == "":
+		conn, err = dialLocalSyslog()
+	case strings.HasPrefix(addr, "tcp://"):
+		conn, err = net.DialTimeout("tcp", strings.TrimPrefix(addr, "tcp://"), 5*time.Second)
+		stream = true
+	default:
+		conn, err = net.Dial("udp", strings.TrimPrefix(addr, "udp://"))
+	}
+	if err != nil {
+		return nil, fmt.Errorf("syslog: %w", err)
+	}
+
+	host, _ := os.Hostname()
+	if host == "" {
+		host = "-"
+	}
+	return &syslogSink{conn: conn, stream: stream, hostname: host, pid: os.Getpid()}, nil
+}
+
+// syslogPriority is facility user (1) with severity informational (6).
+const syslogPriority = 1*8 + 6
+
+func (s *syslogSink) send(events []connEvent) error {
+	for _, ev := range events {
+		msg := fmt.Sprintf("<%d>1 %s %s tcpwatch %d %s - %s",
+			syslogPriority, ev.Updated.Format(time.RFC3339Nano), s.hostname, s.pid, ev.Kind, eventMessage(ev))
+		if s.stream {
+			// Non-transparent framing (RFC 6587) for TCP.
+			msg += "\n"
+		}
+		if _, err := s.conn.Write([]byte(msg)); err != nil {
+			return fmt.Errorf("syslog: %w", err)
+		}
+	}
+	return nil
+}
+
+func (s *syslogSink) Close() error {
+	return s.conn.Close()
+}
@@ -0,0 +1,12 @@
+//go:build windows
+
+package main
+
+import (
+	"errors"
+	"net"
+)
+
+func dialLocalSyslog() (net.Conn, error) {
+	return nil, errors.New("Windows has no local syslog; use -syslog-addr to send to a remote server")
+}
@@ -0,0 +1,23 @@
+//go:build darwin
+
+package main
+
+// tcpStateCodes maps state names to the kernel's numeric TCP states
+// (netinet/tcp_fsm.h). Both gopsutil's and netstat's spellings are listed.
+var tcpStateCodes = map[string]int{
+	"CLOSED":       0,
+	"CLOSE":        0,
+	"LISTEN":       1,
+	"SYN_SENT":     2,
+	"SYN_RECEIVED": 3,
+	"SYN_RECV":     3,
+	"ESTABLISHED":  4,
+	"CLOSE_WAIT":   5,
+	"FIN_WAIT_1":   6,
+	"FIN_WAIT1":    6,
+	"CLOSING":      7,
+	"LAST_ACK":     8,
+	"FIN_WAIT_2":   9,
+	"FIN_WAIT2":    9,
+	"TIME_WAIT":    10,
+}
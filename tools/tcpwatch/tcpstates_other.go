@@ -0,0 +1,7 @@
+//go:build !darwin && !windows && !linux
+
+package main
+
+// tcpStateCodes is empty on unsupported platforms, so -state-codes shows
+// names only.
+var tcpStateCodes = map[string]int{}
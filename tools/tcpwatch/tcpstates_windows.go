@@ -0,0 +1,24 @@
+//go:build windows
+
+package main
+
+// tcpStateCodes maps state names to MIB_TCP_STATE values. Both gopsutil's
+// and netstat's spellings are listed.
+var tcpStateCodes = map[string]int{
+	"CLOSED":       1,
+	"CLOSE":        1,
+	"LISTEN":       2,
+	"SYN_SENT":     3,
+	"SYN_RECEIVED": 4,
+	"SYN_RECV":     4,
+	"ESTABLISHED":  5,
+	"FIN_WAIT1":    6,
+	"FIN_WAIT_1":   6,
+	"FIN_WAIT2":    7,
+	"FIN_WAIT_2":   7,
+	"CLOSE_WAIT":   8,
+	"CLOSING":      9,
+	"LAST_ACK":     10,
+	"TIME_WAIT":    11,
+	"DELETE_TCB":   12,
+}
@@ -0,0 +1,35 @@
+//go:build linux
+
+package main
+
+import (
+	"bufio"
+	"fmt"
+	"os"
+	"strconv"
+	"strings"
+)
+
+// threadGroup returns the thread group ID (the owning process) of a Linux
+// thread ID from /proc/<tid>/status. For a process's main thread it is the
+// PID itself. It returns 0 if the status can't be read.
+func threadGroup(tid int32) int32 {
+	f, err := os.Open(fmt.Sprintf("/proc/%d/status", tid))
+	if err != nil {
+		return 0
+	}
+	defer f.Close()
+	sc := bufio.NewScanner(f)
+	for sc.Scan() {
+		v, ok := strings.CutPrefix(sc.Text(), "Tgid:")
+		if !ok {
+			continue
+		}
+		tgid, err := strconv.ParseInt(strings.TrimSpace(v), 10, 32)
+		if err != nil {
+			return 0
+		}
+		return int32(tgid)
+	}
+	return 0
+}
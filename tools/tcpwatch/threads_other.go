@@ -0,0 +1,9 @@
+//go:build !linux
+
+package main
+
+// Only Linux attributes sockets to thread IDs; -proc-include-threads is a
+// no-op elsewhere.
+func threadGroup(tid int32) int32 {
+	return 0
+}
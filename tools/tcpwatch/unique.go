@@ -0,0 +1,104 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"sort"
+	"time"
+
+	"github.com/bulent/morzer/tools/tcpwatch/internal/render"
+)
+
+// uniqueRemotes is the -count-unique report for one refresh.
+type uniqueRemotes struct {
+	Updated   time.Time         `json:"updated"`
+	By        string            `json:"by"`
+	Count     int               `json:"count"`
+	Remotes   []string          `json:"remotes"`
+	Processes []uniqueByProcess `json:"processes,omitempty"`
+}
+
+type uniqueByProcess struct {
+	PID     int32    `json:"pid"`
+	Process string   `json:"process,omitempty"`
+	Count   int      `json:"count"`
+	Remotes []string `json:"remotes"`
+}
+
+// printUniqueRemotes prints how many distinct remote IPs (by "ip") or
+// IP:port pairs (by "addr") the rows talk to, optionally per process.
+// Listeners have no remote end and aren't counted.
+func printUniqueRemotes(w io.Writer, opts options, rows []render.Row) error {
+	now := time.Now()
+	u := countUnique(rows, opts.countUnique, opts.uniqueByProc, now)
+
+	if opts.jsonLines || opts.jsonOut {
+		enc := json.NewEncoder(w)
+		if opts.jsonOut {
+			enc = jsonOutEncoder(w, opts)
+		}
+		return enc.Encode(u)
+	}
+
+	ts := now.Format(time.RFC3339)
+	fmt.Fprintf(w, "%s unique remotes (by %s): %d\n", ts, u.By, u.Count)
+	for _, p := range u.Processes {
+		process := p.Process
+		if process == "" {
+			process = "-"
+		}
+		fmt.Fprintf(w, "%s   pid=%d %s: %d\n", ts, p.PID, process, p.Count)
+	}
+	return nil
+}
+
+func countUnique(rows []render.Row, by string, perProcess bool, now time.Time) uniqueRemotes {
+	key := func(r render.Row) string {
+		if by == "addr" {
+			return r.Remote
+		}
+		return remoteHost(r.Remote)
+	}
+
+	all := make(map[string]struct{})
+	byPID := make(map[int32]map[string]struct{})
+	names := make(map[int32]string)
+	for _, r := range rows {
+		if remoteHost(r.Remote) == "" {
+			continue
+		}
+		k := key(r)
+		all[k] = struct{}{}
+		if perProcess {
+			if byPID[r.PID] == nil {
+				byPID[r.PID] = make(map[string]struct{})
+				names[r.PID] = r.Process
+			}
+			byPID[r.PID][k] = struct{}{}
+		}
+	}
+
+	u := uniqueRemotes{Updated: now, By: by, Count: len(all), Remotes: sortedKeys(all)}
+	for pid, set := range byPID {
+		u.Processes = append(u.Processes, uniqueByProcess{PID: pid, Process: names[pid], Count: len(set), Remotes: sortedKeys(set)})
+	}
+	// Busiest first, so the process with the most peers leads.
+	sort.Slice(u.Processes, func(i, j int) bool {
+		a, b := u.Processes[i], u.Processes[j]
+		if a.Count != b.Count {
+			return a.Count > b.Count
+		}
+		return a.PID < b.PID
+	})
+	return u
+}
+
+func sortedKeys(set map[string]struct{}) []string {
+	keys := make([]string, 0, len(set))
+	for k := range set {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	return keys
+}
@@ -0,0 +1,84 @@
+package main
+
+import (
+	"fmt"
+	"io"
+	"net"
+	"net/netip"
+	"strconv"
+	"strings"
+
+	"github.com/bulent/morzer/tools/tcpwatch/internal/render"
+)
+
+// knownStates are the state names any supported platform or backend
+// reports, plus the placeholders tcpwatch uses itself.
+var knownStates = map[string]bool{
+	"ESTABLISHED": true, "SYN_SENT": true, "SYN_RECV": true, "SYN_RECEIVED": true,
+	"FIN_WAIT1": true, "FIN_WAIT_1": true, "FIN_WAIT2": true, "FIN_WAIT_2": true,
+	"TIME_WAIT": true, "CLOSE": true, "CLOSED": true, "CLOSE_WAIT": true,
+	"LAST_ACK": true, "LISTEN": true, "CLOSING": true, "NEW_SYN_RECV": true,
+	"DELETE_TCB": true, "NONE": true, "UNKNOWN": true, "-": true,
+}
+
+// rowProblem returns why r looks like garbage, or "" if it is sane. Hosts
+// aren't checked when redacted, since -redact replaces them with hashes.
+func rowProblem(r render.Row, redacted bool) string {
+	if r.PID < 0 {
+		return "negative PID"
+	}
+	if !knownStates[r.State] {
+		return fmt.Sprintf("unknown state %q", r.State)
+	}
+	if r.Proto == "unix" {
+		return ""
+	}
+	if !validAddr(r.Local, redacted) {
+		return fmt.Sprintf("bad local address %q", r.Local)
+	}
+	if !validAddr(r.Remote, redacted) {
+		return fmt.Sprintf("bad remote address %q", r.Remote)
+	}
+	return ""
+}
+
+// validAddr reports whether addr is a host:port as formatAddr writes it.
+func validAddr(addr string, redacted bool) bool {
+	host, port, err := net.SplitHostPort(addr)
+	if err != nil {
+		return false
+	}
+	if port != "*" {
+		if _, err := strconv.ParseUint(port, 10, 16); err != nil {
+			return false
+		}
+	}
+	if redacted || host == "*" || host == "localhost" {
+		return true
+	}
+	_, err = netip.ParseAddr(host)
+	return err == nil
+}
+
+// validateRows checks rows for -strict, warning on w about every invalid one.
+// In "drop" mode invalid rows are left out; in "flag" mode they are kept with
+// Invalid set. rows itself is left unchanged.
+func validateRows(w io.Writer, rows []render.Row, mode string, redacted bool) []render.Row {
+	out := make([]render.Row, 0, len(rows))
+	for _, r := range rows {
+		problem := rowProblem(r, redacted)
+		if problem == "" {
+			out = append(out, r)
+			continue
+		}
+		row := strings.Join([]string{r.Proto, r.Local, r.Remote, r.State, strconv.Itoa(int(r.PID))}, " ")
+		if mode == "flag" {
+			fmt.Fprintf(w, "tcpwatch: -strict: invalid row (%s): %s\n", problem, row)
+			r.Invalid = problem
+			out = append(out, r)
+			continue
+		}
+		fmt.Fprintf(w, "tcpwatch: -strict: dropped row (%s): %s\n", problem, row)
+	}
+	return out
+}
@@ -0,0 +1,73 @@
+package main
+
+import (
+	"time"
+
+	"github.com/bulent/morzer/tools/tcpwatch/internal/render"
+)
+
+// watchState carries data between refreshes of the watch loop.
+type watchState struct {
+	// prev holds the previous refresh's rows for -events; primed is set
+	// once a baseline has been recorded.
+	prev   map[connKey]render.Row
+	primed bool
+	// renderer writes the connection listings; printRows selects it on the
+	// first refresh.
+	renderer renderer
+	// firstSeen records when each connection was first listed, for -age.
+	firstSeen map[connKey]time.Time
+	// seqs holds the -seq number of each listed connection; lastSeq is the
+	// last number handed out.
+	seqs    map[connKey]int
+	lastSeq int
+	// lastRows is the previous -jsonl snapshot's encoded rows for
+	// -merge-repeats; repeats counts the identical snapshots suppressed since
+	// and repeatUpdated is the time of the latest.
+	lastRows      []byte
+	repeats       int
+	repeatUpdated time.Time
+	// listeners holds the LISTEN sockets of the previous refresh, for
+	// -watch-new-listener; listenersSince is when watching started.
+	listeners      map[listenerKey]struct{}
+	listenersSince time.Time
+	// stateSince records when each connection entered its current state,
+	// for -stuck.
+	stateSince map[connKey]stateEntry
+	// remoteSeen holds the connections -watch-only-remote-port has already
+	// alerted on.
+	remoteSeen map[connKey]struct{}
+	// lastClear is when the screen was last cleared, and inPlace is set while
+	// a refresh is drawn over the previous one (-clear-rate).
+	lastClear time.Time
+	inPlace   bool
+	// growth holds the recent ESTABLISHED counts, oldest first, for
+	// -growth-alert.
+	growth []growthSample
+	// privHinted is set once the missing-privileges hint has been shown.
+	privHinted bool
+	// metrics, when set, is updated with every refresh's rows
+	// (-metrics-addr).
+	metrics *metricsExporter
+	// recorder, when set, saves every refresh's rows (-record).
+	recorder *recorder
+	// sinks receive every refresh's -events (e.g. syslog).
+	sinks []eventSink
+	// fileBaseline is the snapshot loaded for -watch-compare-baseline-file.
+	// Unlike prev it never changes.
+	fileBaseline *fileBaseline
+}
+
+// reset forgets everything learned from previous refreshes, so the next one
+// starts from scratch. Sinks and the file baseline are kept.
+func (st *watchState) reset() {
+	st.prev = nil
+	st.primed = false
+	st.firstSeen = nil
+	st.stateSince = nil
+	st.lastRows = nil
+	st.listeners = nil
+	st.remoteSeen = nil
+	st.growth = nil
+	st.seqs = nil
+}
@@ -0,0 +1,114 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/url"
+	"os"
+	"time"
+)
+
+// Each -webhook POST gets webhookTimeout, and a failed one is retried once
+// after webhookRetryDelay unless the server rejected the request (4xx). Up to
+// webhookQueue payloads wait for delivery; more are dropped.
+const (
+	webhookTimeout    = 5 * time.Second
+	webhookRetryDelay = time.Second
+	webhookQueue      = 16
+)
+
+// webhookSink POSTs each refresh's -events to an HTTP endpoint as one JSON
+// object: {"host": ..., "events": [...]}, the events in the -jsonl format.
+// -growth-alert alerts are posted as {"host": ..., "growth": {...}}. The
+// POSTs happen in the background, so a slow or unreachable endpoint never
+// holds up a refresh; delivery errors are logged to stderr.
+type webhookSink struct {
+	url        string
+	client     *http.Client
+	hostname   string
+	retryDelay time.Duration
+	queue      chan webhookPayload
+}
+
+// newWebhookSink returns a sink posting to rawURL. host names the machine in
+// the payload; empty means the hostname.
+func newWebhookSink(rawURL, host string) (*webhookSink, error) {
+	u, err := url.Parse(rawURL)
+	if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+		return nil, fmt.Errorf("-webhook: want an http:// or https:// URL, got %q", rawURL)
+	}
+	if host == "" {
+		host, _ = os.Hostname()
+	}
+	s := &webhookSink{
+		url:        rawURL,
+		client:     &http.Client{Timeout: webhookTimeout},
+		hostname:   host,
+		retryDelay: webhookRetryDelay,
+		queue:      make(chan webhookPayload, webhookQueue),
+	}
+	go s.deliver()
+	return s, nil
+}
+
+type webhookPayload struct {
+	Host   string       `json:"host,omitempty"`
+	Events []connEvent  `json:"events,omitempty"`
+	Growth *growthAlert `json:"growth,omitempty"`
+}
+
+func (s *webhookSink) send(events []connEvent) error {
+	return s.enqueue(webhookPayload{Host: s.hostname, Events: events})
+}
+
+func (s *webhookSink) sendGrowth(a growthAlert) error {
+	return s.enqueue(webhookPayload{Host: s.hostname, Growth: &a})
+}
+
+// enqueue hands p to deliver without blocking. It fails when the queue is
+// full, i.e. when the endpoint has fallen behind.
+func (s *webhookSink) enqueue(p webhookPayload) error {
+	select {
+	case s.queue <- p:
+		return nil
+	default:
+		return fmt.Errorf("webhook: %s is not keeping up; dropped a payload", s.url)
+	}
+}
+
+// deliver posts the queued payloads one at a time.
+func (s *webhookSink) deliver() {
+	for p := range s.queue {
+		if err := s.postPayload(p); err != nil {
+			fmt.Fprintln(os.Stderr, err)
+		}
+	}
+}
+
+func (s *webhookSink) postPayload(p webhookPayload) error {
+	body, err := json.Marshal(p)
+	if err != nil {
+		return fmt.Errorf("webhook: %w", err)
+	}
+	retry, err := s.post(body)
+	if err != nil && retry {
+		time.Sleep(s.retryDelay)
+		_, err = s.post(body)
+	}
+	return err
+}
+
+// post sends body once. It reports whether a failure is worth retrying.
+func (s *webhookSink) post(body []byte) (retry bool, err error) {
+	resp, err := s.client.Post(s.url, "application/json", bytes.NewReader(body))
+	if err != nil {
+		return true, fmt.Errorf("webhook: %w", err)
+	}
+	resp.Body.Close()
+	if resp.StatusCode >= 300 {
+		return resp.StatusCode >= 500, fmt.Errorf("webhook: %s returned %s", s.url, resp.Status)
+	}
+	return false, nil
+}
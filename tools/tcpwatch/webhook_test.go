@@ -0,0 +1,115 @@
+package main
+
+import (
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/bulent/morzer/tools/tcpwatch/internal/render"
+)
+
+func TestWebhookPayload(t *testing.T) {
+	bodies := make(chan []byte, 2)
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if ct := r.Header.Get("Content-Type"); ct != "application/json" {
+			t.Errorf("Content-Type %q, want application/json", ct)
+		}
+		b, _ := io.ReadAll(r.Body)
+		bodies <- b
+	}))
+	defer srv.Close()
+
+	s, err := newWebhookSink(srv.URL, "web-01")
+	if err != nil {
+		t.Fatal(err)
+	}
+	updated := time.Date(2026, 1, 2, 3, 4, 5, 0, time.UTC)
+	ev := connEvent{Updated: updated, Kind: eventAdded, Row: render.Row{Proto: "tcp4", Local: "10.0.0.5:22", Remote: "10.0.0.9:40000", State: "ESTABLISHED", PID: 7, Process: "sshd"}}
+	if err := s.send([]connEvent{ev}); err != nil {
+		t.Fatal(err)
+	}
+	if err := s.sendGrowth(growthAlert{Updated: updated, Window: time.Minute, From: 10, To: 60}); err != nil {
+		t.Fatal(err)
+	}
+
+	want := []string{
+		`{"host":"web-01","events":[{"updated":"2026-01-02T03:04:05Z","event":"added","row":{"Proto":"tcp4","Local":"10.0.0.5:22","Remote":"10.0.0.9:40000","State":"ESTABLISHED","PID":7,"Process":"sshd","Listening":false}}]}`,
+		`{"host":"web-01","growth":{"updated":"2026-01-02T03:04:05Z","window":60000000000,"from":10,"to":60}}`,
+	}
+	for _, w := range want {
+		select {
+		case b := <-bodies:
+			if string(b) != w {
+				t.Errorf("payload\n%s\nwant\n%s", b, w)
+			}
+		case <-time.After(5 * time.Second):
+			t.Fatal("no payload posted")
+		}
+	}
+}
+
+// Server errors (5xx) and failed connections are retried once; a rejected
+// request (4xx) is not.
+func TestWebhookRetry(t *testing.T) {
+	for _, tt := range []struct {
+		status   int
+		attempts int32
+		fail     bool
+	}{
+		{http.StatusOK, 1, false},
+		{http.StatusBadRequest, 1, true},
+		{http.StatusInternalServerError, 2, true},
+		{http.StatusServiceUnavailable, 2, true},
+	} {
+		var n atomic.Int32
+		srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			n.Add(1)
+			w.WriteHeader(tt.status)
+		}))
+		s, err := newWebhookSink(srv.URL, "web-01")
+		if err != nil {
+			t.Fatal(err)
+		}
+		s.retryDelay = 0
+		err = s.postPayload(webhookPayload{Host: "web-01"})
+		srv.Close()
+		if (err != nil) != tt.fail {
+			t.Errorf("status %d: err = %v, want failure %v", tt.status, err, tt.fail)
+		}
+		if got := n.Load(); got != tt.attempts {
+			t.Errorf("status %d: %d attempts, want %d", tt.status, got, tt.attempts)
+		}
+	}
+}
+
+// A stalled endpoint must not hold up the refresh that sends to it: send
+// returns at once, and payloads beyond the queue are dropped.
+func TestWebhookSendDoesNotBlock(t *testing.T) {
+	release := make(chan struct{})
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		<-release
+	}))
+	defer srv.Close()
+	defer close(release)
+
+	s, err := newWebhookSink(srv.URL, "web-01")
+	if err != nil {
+		t.Fatal(err)
+	}
+	start := time.Now()
+	dropped := 0
+	for range webhookQueue + 2 {
+		if err := s.send([]connEvent{{Kind: eventAdded}}); err != nil {
+			dropped++
+		}
+	}
+	if d := time.Since(start); d > time.Second {
+		t.Errorf("send blocked for %s", d)
+	}
+	if dropped == 0 {
+		t.Error("no payload dropped with the queue full")
+	}
+}
@@ -0,0 +1,41 @@
+package main
+
+import (
+	"cmp"
+	"context"
+	"fmt"
+	"io"
+	"slices"
+
+	"github.com/bulent/morzer/tools/tcpwatch/internal/render"
+)
+
+// printWhoListens answers -who-listens: it prints "PID NAME" for each
+// process listening on opts.whoListens, by PID, and reports whether there
+// was one.
+func printWhoListens(ctx context.Context, w io.Writer, opts options, procs *procResolver) (bool, error) {
+	rows, err := listTCP(ctx, listOptions(opts), procs)
+	if err != nil {
+		return false, err
+	}
+	seen := make(map[int32]bool)
+	var owners []render.Row
+	for _, r := range rows {
+		if !r.Listening || addrPort(r.Local) != uint32(opts.whoListens) || seen[r.PID] {
+			continue
+		}
+		seen[r.PID] = true
+		owners = append(owners, r)
+	}
+	slices.SortFunc(owners, func(a, b render.Row) int { return cmp.Compare(a.PID, b.PID) })
+	for _, r := range owners {
+		process := r.Process
+		if process == "" {
+			process = "-"
+		}
+		if _, err := fmt.Fprintf(w, "%d %s\n", r.PID, process); err != nil {
+			return true, err
+		}
+	}
+	return len(owners) > 0, nil
+}
@@ -0,0 +1,65 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"strconv"
+	"syscall"
+	"testing"
+	"time"
+
+	gnet "github.com/shirou/gopsutil/v4/net"
+)
+
+func TestWhoListens(t *testing.T) {
+	src := fakeSource{
+		conns: []gnet.ConnectionStat{
+			// SO_REUSEPORT workers, listed out of PID order.
+			tcpConn(syscall.AF_INET, gnet.Addr{IP: "0.0.0.0", Port: 443}, gnet.Addr{IP: "0.0.0.0"}, "LISTEN", 31),
+			tcpConn(syscall.AF_INET6, gnet.Addr{IP: "::", Port: 443}, gnet.Addr{IP: "::"}, "LISTEN", 31),
+			tcpConn(syscall.AF_INET, gnet.Addr{IP: "0.0.0.0", Port: 443}, gnet.Addr{IP: "0.0.0.0"}, "LISTEN", 12),
+			tcpConn(syscall.AF_INET, gnet.Addr{IP: "0.0.0.0", Port: 443}, gnet.Addr{IP: "0.0.0.0"}, "LISTEN", 20),
+			tcpConn(syscall.AF_INET, gnet.Addr{IP: "0.0.0.0", Port: 22}, gnet.Addr{IP: "*"}, "NONE", 7),
+			// A client of port 443 elsewhere doesn't listen on it.
+			tcpConn(syscall.AF_INET, gnet.Addr{IP: "10.0.0.5", Port: 50000}, gnet.Addr{IP: "192.0.2.1", Port: 443}, "ESTABLISHED", 99),
+		},
+		names: procNames{31: "nginx", 12: "nginx", 7: "sshd", 99: "curl"},
+	}
+	tests := []struct {
+		port  int
+		found bool
+		want  string
+	}{
+		{443, true, "12 nginx\n20 -\n31 nginx\n"},
+		{22, true, "7 sshd\n"},
+		{8080, false, ""},
+	}
+	for _, tt := range tests {
+		opts, err := parseFlags([]string{"-who-listens", strconv.Itoa(tt.port)})
+		if err != nil {
+			t.Fatal(err)
+		}
+		opts.source = src
+		procs := newProcResolver(time.Minute, opts.procCacheSize, opts.resolveMethod)
+		procs.cache.put(20, procCacheEntry{until: time.Now().Add(time.Minute)})
+		var b bytes.Buffer
+		found, err := printWhoListens(context.Background(), &b, opts, procs)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if found != tt.found || b.String() != tt.want {
+			t.Errorf("port %d: found %v, output %q; want %v, %q", tt.port, found, b.String(), tt.found, tt.want)
+		}
+	}
+}
+
+func TestWhoListensRejectsOtherStates(t *testing.T) {
+	if _, err := parseFlags([]string{"-who-listens", "443", "-state", "LISTEN"}); err != nil {
+		t.Errorf("-state LISTEN: %v", err)
+	}
+	for _, states := range []string{"ESTABLISHED", "LISTEN,ESTABLISHED"} {
+		if _, err := parseFlags([]string{"-who-listens", "443", "-state", states}); err == nil {
+			t.Errorf("-state %s accepted", states)
+		}
+	}
+}